@@ -0,0 +1,421 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go"
+	"github.com/mgtv-tech/jetcache-go/encoding"
+	"github.com/mgtv-tech/jetcache-go/logger"
+)
+
+const (
+	tierLocal = "local"
+	tierBoth  = "both"
+)
+
+var (
+	errCacheNotFound    = errors.New("admin: cache not found")
+	errKeyRequired      = errors.New("admin: key is required")
+	errChecksumMismatch = errors.New("admin: checksum mismatch")
+	errNotSupported     = errors.New("admin: operation not supported by the cache")
+)
+
+type (
+	// Handler is an http.Handler to inspect and mutate named caches on a live process.
+	// Mount it under a prefix with http.StripPrefix. It serves:
+	//
+	//	GET    /                                 lists the caches.
+	//	GET    /{name}                           shows the config and stats of a cache.
+	//	GET    /{name}/key?key=k                 reads the raw key from each tier, decoded if the codec can decode into any.
+	//	DELETE /{name}/key?key=k[&tier=local]    deletes the key from local or both tiers.
+	//	GET    /{name}/refresh                   lists the refresh tasks.
+	//	POST   /{name}/refresh?key=k             refreshes the auto-refresh key now.
+	//	POST   /{name}/refresh?op=cancel&key=k   cancels the refresh task of the key.
+	//	POST   /{name}/refresh?op=pause          pauses refreshing, op=resume resumes it.
+	Handler struct {
+		mu          sync.RWMutex
+		caches      map[string]cache.Cache
+		middlewares []func(http.Handler) http.Handler
+		handler     http.Handler
+	}
+
+	// Option defines the method to customize a Handler.
+	Option func(h *Handler)
+
+	cacheView struct {
+		Name      string `json:"name"`
+		CacheType string `json:"cacheType"`
+	}
+
+	statsView struct {
+		Hit        uint64     `json:"hit"`
+		Miss       uint64     `json:"miss"`
+		LocalHit   uint64     `json:"localHit"`
+		LocalMiss  uint64     `json:"localMiss"`
+		RemoteHit  uint64     `json:"remoteHit"`
+		RemoteMiss uint64     `json:"remoteMiss"`
+		Query      uint64     `json:"query"`
+		QueryFail  uint64     `json:"queryFail"`
+		Local      *localView `json:"local,omitempty"`
+	}
+
+	localView struct {
+		Entries     int64 `json:"entries"`
+		Bytes       int64 `json:"bytes"`
+		Capacity    int64 `json:"capacity"`
+		Evictions   int64 `json:"evictions"`
+		Expirations int64 `json:"expirations"`
+		Overwrites  int64 `json:"overwrites"`
+		Rejections  int64 `json:"rejections"`
+	}
+
+	tierView struct {
+		Tier        string `json:"tier"`
+		Found       bool   `json:"found"`
+		Placeholder bool   `json:"placeholder,omitempty"`
+		Raw         []byte `json:"raw,omitempty"`
+		Decoded     any    `json:"decoded,omitempty"`
+		DecodeErr   string `json:"decodeErr,omitempty"`
+	}
+
+	refreshTaskView struct {
+		Key             string    `json:"key"`
+		RefreshDuration string    `json:"refreshDuration"`
+		LastAccessTime  time.Time `json:"lastAccessTime"`
+		LastRefreshTime time.Time `json:"lastRefreshTime"`
+		LastErr         string    `json:"lastErr,omitempty"`
+		NextRefreshTime time.Time `json:"nextRefreshTime"`
+	}
+)
+
+// WithCaches registers the caches by their names, see Handler.Register. It
+// panics if two caches have the same name.
+func WithCaches(caches ...cache.Cache) Option {
+	return func(h *Handler) {
+		for _, c := range caches {
+			if err := h.register(c); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// WithMiddleware wraps the handler with the middlewares, such as authentication.
+// The first middleware is the outermost one.
+func WithMiddleware(middlewares ...func(http.Handler) http.Handler) Option {
+	return func(h *Handler) {
+		h.middlewares = append(h.middlewares, middlewares...)
+	}
+}
+
+// BasicAuth returns a middleware that requires HTTP basic authentication.
+func BasicAuth(username, password string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, p, ok := r.BasicAuth()
+			if !ok ||
+				subtle.ConstantTimeCompare([]byte(u), []byte(username)) != 1 ||
+				subtle.ConstantTimeCompare([]byte(p), []byte(password)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Basic realm="jetcache-go"`)
+				writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// NewHandler creates a new admin Handler.
+func NewHandler(opts ...Option) *Handler {
+	h := &Handler{
+		caches: make(map[string]cache.Cache),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.handler = http.HandlerFunc(h.serve)
+	for i := len(h.middlewares) - 1; i >= 0; i-- {
+		h.handler = h.middlewares[i](h.handler)
+	}
+
+	return h
+}
+
+// Register registers the cache by its name. It returns an error if a cache of
+// the same name is already registered. The cache must implement
+// cache.Inspector, as the caches created by cache.New do. The delete and
+// refresh endpoints also require cache.Invalidator and cache.RefreshController.
+func (h *Handler) Register(c cache.Cache) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.register(c)
+}
+
+func (h *Handler) register(c cache.Cache) error {
+	i, ok := c.(cache.Inspector)
+	if !ok {
+		logger.Warn("admin#Register cache(%s) does not implement cache.Inspector, skipped", c.CacheType())
+		return nil
+	}
+
+	name := i.Config().Name
+	if _, ok := h.caches[name]; ok {
+		return fmt.Errorf("admin: cache %q already registered", name)
+	}
+	h.caches[name] = c
+
+	return nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.handler.ServeHTTP(w, r)
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		h.list(w)
+		return
+	}
+
+	name, action, _ := strings.Cut(path, "/")
+	h.mu.RLock()
+	c, ok := h.caches[name]
+	h.mu.RUnlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, errCacheNotFound)
+		return
+	}
+
+	switch action {
+	case "":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		h.info(w, c.(cache.Inspector))
+	case "key":
+		switch r.Method {
+		case http.MethodGet:
+			h.get(w, r, c.(cache.Inspector))
+		case http.MethodDelete:
+			h.delete(w, r, c)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+	case "refresh":
+		rc, ok := c.(cache.RefreshController)
+		if !ok {
+			writeError(w, http.StatusNotImplemented, errNotSupported)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			h.refreshTasks(w, rc)
+		case http.MethodPost:
+			h.refresh(w, r, rc)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("admin: unknown path %q", r.URL.Path))
+	}
+}
+
+func (h *Handler) list(w http.ResponseWriter) {
+	h.mu.RLock()
+	views := make([]cacheView, 0, len(h.caches))
+	for name, c := range h.caches {
+		views = append(views, cacheView{Name: name, CacheType: c.CacheType()})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Name < views[j].Name
+	})
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (h *Handler) info(w http.ResponseWriter, c cache.Inspector) {
+	s := c.Stats()
+	view := statsView{
+		Hit:        s.Hit,
+		Miss:       s.Miss,
+		LocalHit:   s.LocalHit,
+		LocalMiss:  s.LocalMiss,
+		RemoteHit:  s.RemoteHit,
+		RemoteMiss: s.RemoteMiss,
+		Query:      s.Query,
+		QueryFail:  s.QueryFail,
+	}
+	if s.Local.Capacity > 0 {
+		view.Local = &localView{
+			Entries:     s.Local.Entries,
+			Bytes:       s.Local.Bytes,
+			Capacity:    s.Local.Capacity,
+			Evictions:   s.Local.Evictions,
+			Expirations: s.Local.Expirations,
+			Overwrites:  s.Local.Overwrites,
+			Rejections:  s.Local.Rejections,
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"config": c.Config(),
+		"stats":  view,
+	})
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, c cache.Inspector) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, errKeyRequired)
+		return
+	}
+
+	values, err := c.Peek(r.Context(), key)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	codecName := c.Config().Codec
+	codec := encoding.GetCodec(codecName)
+	views := make([]tierView, 0, len(values))
+	for _, v := range values {
+		view := tierView{
+			Tier:        v.Tier,
+			Found:       v.Found,
+			Placeholder: v.Placeholder,
+			Raw:         v.Raw,
+		}
+		switch {
+		case !v.Found || v.Placeholder || len(v.Raw) == 0:
+		case v.Value == nil:
+			view.DecodeErr = errChecksumMismatch.Error()
+		default:
+			// codecs such as proto and gob cannot decode into any, the raw
+			// value is then returned alone, to be decoded with the codec.
+			if err := codec.Unmarshal(v.Value, &view.Decoded); err != nil {
+				view.Decoded = nil
+			}
+		}
+		views = append(views, view)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"key":   key,
+		"codec": codecName,
+		"tiers": views,
+	})
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, c cache.Cache) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		writeError(w, http.StatusBadRequest, errKeyRequired)
+		return
+	}
+
+	switch tier := r.URL.Query().Get("tier"); tier {
+	case tierLocal:
+		c.DeleteFromLocalCache(key)
+	case tierBoth, "":
+		i, ok := c.(cache.Invalidator)
+		if !ok {
+			writeError(w, http.StatusNotImplemented, errNotSupported)
+			return
+		}
+		if err := i.Invalidate(r.Context(), key); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("admin: unknown tier %q", tier))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (h *Handler) refreshTasks(w http.ResponseWriter, c cache.RefreshController) {
+	tasks := c.RefreshTasks()
+	views := make([]refreshTaskView, 0, len(tasks))
+	for _, task := range tasks {
+		view := refreshTaskView{
+			Key:             task.Key,
+			RefreshDuration: task.RefreshDuration.String(),
+			LastAccessTime:  task.LastAccessTime,
+			LastRefreshTime: task.LastRefreshTime,
+			NextRefreshTime: task.NextRefreshTime,
+		}
+		if task.LastErr != nil {
+			view.LastErr = task.LastErr.Error()
+		}
+		views = append(views, view)
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (h *Handler) refresh(w http.ResponseWriter, r *http.Request, c cache.RefreshController) {
+	key := r.URL.Query().Get("key")
+	switch op := r.URL.Query().Get("op"); op {
+	case "refresh", "":
+		if key == "" {
+			writeError(w, http.StatusBadRequest, errKeyRequired)
+			return
+		}
+		if err := c.RefreshNow(r.Context(), key); errors.Is(err, cache.ErrRefreshTaskNotFound) {
+			writeError(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	case "cancel":
+		if key == "" {
+			writeError(w, http.StatusBadRequest, errKeyRequired)
+			return
+		}
+		c.CancelRefresh(key)
+	case "pause":
+		c.PauseRefresh()
+	case "resume":
+		c.ResumeRefresh()
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("admin: unknown op %q", op))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func methodNotAllowed(w http.ResponseWriter, methods ...string) {
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,289 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go"
+	"github.com/mgtv-tech/jetcache-go/encoding/gob"
+	"github.com/mgtv-tech/jetcache-go/local"
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+type object struct {
+	Str string
+	Num int
+}
+
+func newRdb() *redis.Client {
+	s, err := miniredis.Run()
+	if err != nil {
+		panic(err)
+	}
+
+	return redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+}
+
+// mockWriter records the keys deleted from the data source.
+type mockWriter struct {
+	deletes []string
+}
+
+func (w *mockWriter) Write(context.Context, string, any) error {
+	return nil
+}
+
+func (w *mockWriter) Delete(_ context.Context, key string) error {
+	w.deletes = append(w.deletes, key)
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	writer := &mockWriter{}
+	both := cache.New(cache.WithName("both"),
+		cache.WithRemote(remote.NewGoRedisV9Adapter(newRdb())),
+		cache.WithLocal(local.NewFreeCache(10*local.MB, time.Minute, "admin")),
+		cache.WithRefreshDuration(time.Minute),
+		cache.WithWriter(writer))
+	defer both.Close()
+	onlyLocal := cache.New(cache.WithName("local"),
+		cache.WithLocal(local.NewTinyLFU(1000, time.Minute)))
+	defer onlyLocal.Close()
+
+	h := NewHandler(WithCaches(both))
+	assert.NoError(t, h.Register(onlyLocal))
+	assert.EqualError(t, h.Register(onlyLocal), `admin: cache "local" already registered`)
+	assert.Panics(t, func() { NewHandler(WithCaches(both, both)) })
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	assert.NoError(t, both.Set(ctx, "k1", cache.Value(&object{Str: "str", Num: 1})))
+
+	t.Run("list caches", func(t *testing.T) {
+		w := serve(http.MethodGet, "/")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `[{"name":"both","cacheType":"both"},{"name":"local","cacheType":"local"}]`, w.Body.String())
+	})
+
+	t.Run("cache info", func(t *testing.T) {
+		var info struct {
+			Config cache.Config
+			Stats  statsView
+		}
+		w := serve(http.MethodGet, "/both")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &info))
+		assert.Equal(t, "both", info.Config.Name)
+		assert.Equal(t, "msgpack", info.Config.Codec)
+		assert.Equal(t, int64(10*local.MB), info.Stats.Local.Capacity)
+		assert.Equal(t, int64(1), info.Stats.Local.Entries)
+
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodGet, "/any").Code)
+	})
+
+	t.Run("get key", func(t *testing.T) {
+		var resp struct {
+			Key   string
+			Codec string
+			Tiers []tierView
+		}
+		w := serve(http.MethodGet, "/both/key?key=k1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "k1", resp.Key)
+		assert.Equal(t, "msgpack", resp.Codec)
+		assert.Len(t, resp.Tiers, 2)
+		for _, tier := range resp.Tiers {
+			assert.True(t, tier.Found)
+			assert.NotEmpty(t, tier.Raw)
+			assert.Equal(t, map[string]any{"Str": "str", "Num": float64(1)}, tier.Decoded)
+		}
+
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/both/key").Code)
+	})
+
+	t.Run("delete key", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/both/key?key=k1&tier=local").Code)
+		values, err := both.(cache.Inspector).Peek(ctx, "k1")
+		assert.NoError(t, err)
+		assert.False(t, values[0].Found)
+		assert.True(t, values[1].Found)
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodDelete, "/both/key?key=k1").Code)
+		values, err = both.(cache.Inspector).Peek(ctx, "k1")
+		assert.NoError(t, err)
+		assert.False(t, values[1].Found)
+		// purging the cache does not delete the data.
+		assert.Empty(t, writer.deletes)
+
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodDelete, "/both/key?key=k1&tier=any").Code)
+	})
+
+	t.Run("refresh key", func(t *testing.T) {
+		err := both.Once(ctx, "k2", cache.Refresh(true), cache.Do(func(context.Context) (any, error) {
+			return "v2", nil
+		}))
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/both/refresh?key=k2").Code)
+		assert.Equal(t, http.StatusNotFound, serve(http.MethodPost, "/both/refresh?key=k3").Code)
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/both/refresh").Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodDelete, "/both/refresh?key=k2").Code)
+	})
+
+	t.Run("refresh tasks", func(t *testing.T) {
+		err := both.Once(ctx, "k4", cache.Refresh(true), cache.Do(func(context.Context) (any, error) {
+			return nil, errors.New("any")
+		}))
+		assert.Error(t, err)
+
+		w := serve(http.MethodPost, "/both/refresh?key=k4")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "any")
+
+		var views []refreshTaskView
+		w = serve(http.MethodGet, "/both/refresh")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &views))
+		assert.Len(t, views, 2)
+		assert.Equal(t, "k2", views[0].Key)
+		assert.Equal(t, "1m0s", views[0].RefreshDuration)
+		assert.Empty(t, views[0].LastErr)
+		assert.Equal(t, "k4", views[1].Key)
+		assert.Equal(t, "any", views[1].LastErr)
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/both/refresh?op=pause").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/both/refresh?key=k2").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/both/refresh?op=resume").Code)
+
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/both/refresh?op=cancel").Code)
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/both/refresh?op=cancel&key=k4").Code)
+		assert.Equal(t, 1, both.TaskSize())
+		assert.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/both/refresh?op=any").Code)
+	})
+}
+
+// inspectorCache implements cache.Inspector but no other optional interface.
+type inspectorCache struct {
+	cache.Cache
+	cache.Inspector
+}
+
+func TestHandlerOptionalInterfaces(t *testing.T) {
+	c := cache.New(cache.WithName("plain"), cache.WithLocal(local.NewTinyLFU(1000, time.Minute)))
+	defer c.Close()
+
+	h := NewHandler()
+	assert.NoError(t, h.Register(struct{ cache.Cache }{c}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	assert.NoError(t, h.Register(inspectorCache{Cache: c, Inspector: c.(cache.Inspector)}))
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/plain", nil),
+		httptest.NewRequest(http.MethodDelete, "/plain/key?key=k1", nil),
+		httptest.NewRequest(http.MethodPost, "/plain/refresh?key=k1", nil),
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if r.Method == http.MethodGet {
+			assert.Equal(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusNotImplemented, w.Code)
+		}
+	}
+}
+
+func TestHandlerUndecodable(t *testing.T) {
+	ctx := context.Background()
+	c := cache.New(cache.WithName("gob"),
+		cache.WithLocal(local.NewTinyLFU(1000, time.Minute)),
+		cache.WithCodec(gob.Name))
+	defer c.Close()
+	h := NewHandler(WithCaches(c))
+
+	// gob cannot decode into any, the raw value is returned with the codec.
+	assert.NoError(t, c.Set(ctx, "k1", cache.Value(&object{Str: "str", Num: 1})))
+	var resp struct {
+		Codec string
+		Tiers []tierView
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/gob/key?key=k1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, gob.Name, resp.Codec)
+	assert.Len(t, resp.Tiers, 1)
+	assert.True(t, resp.Tiers[0].Found)
+	assert.NotEmpty(t, resp.Tiers[0].Raw)
+	assert.Nil(t, resp.Tiers[0].Decoded)
+	assert.Empty(t, resp.Tiers[0].DecodeErr)
+}
+
+func TestHandlerChecksum(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRdb()
+	c := cache.New(cache.WithName("checksum"),
+		cache.WithRemote(remote.NewGoRedisV9Adapter(rdb)),
+		cache.WithLocal(local.NewFreeCache(10*local.MB, time.Minute, "checksum")),
+		cache.WithChecksum(cache.NewCRC32Checksum()))
+	defer c.Close()
+	h := NewHandler(WithCaches(c))
+
+	get := func(key string) []tierView {
+		var resp struct {
+			Tiers []tierView
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/checksum/key?key="+key, nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Tiers
+	}
+
+	assert.NoError(t, c.Set(ctx, "k1", cache.Value(&object{Str: "str", Num: 1})))
+	tiers := get("k1")
+	assert.Len(t, tiers, 2)
+	for _, tier := range tiers {
+		assert.Empty(t, tier.DecodeErr)
+		assert.Equal(t, map[string]any{"Str": "str", "Num": float64(1)}, tier.Decoded)
+	}
+
+	// a value written without the trailer fails the check.
+	assert.NoError(t, rdb.Set(ctx, "k2", "foreign", time.Minute).Err())
+	tiers = get("k2")
+	assert.True(t, tiers[1].Found)
+	assert.Nil(t, tiers[1].Decoded)
+	assert.Equal(t, errChecksumMismatch.Error(), tiers[1].DecodeErr)
+}
+
+func TestBasicAuth(t *testing.T) {
+	h := NewHandler(WithMiddleware(BasicAuth("user", "pass")))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.SetBasicAuth("user", "pass")
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+const (
+	defaultLoadConcurrency = 4
+	maxFlightAttempts      = 3 // attempts of an MGet to share or own the flights of its ids.
+)
+
+// ItemStatus is the status of an id in a BatchResult.
+type ItemStatus int
+
+const (
+	StatusMiss        ItemStatus = iota // Not cached, and not loaded as the load function is nil.
+	StatusLocalHit                      // Read from the local cache.
+	StatusRemoteHit                     // Read from the remote cache.
+	StatusLoaded                        // Loaded by the load function.
+	StatusNotFound                      // Cached as not found, or missing from the load function result.
+	StatusDecodeError                   // Cached, but failed to decode.
+	StatusLoadError                     // The load function failed.
+	StatusEncodeError                   // Loaded, but failed to encode, so it is not cached. Value is set.
+)
+
+type (
+	// ItemResult is the result of an id in a BatchResult.
+	ItemResult[V any] struct {
+		Value  V
+		Status ItemStatus
+		Err    error // The decode, load or encode error.
+	}
+
+	// BatchResult is the result of T.MGetResults, with the result of each id.
+	BatchResult[K constraints.Ordered, V any] struct {
+		Items map[K]ItemResult[V]
+		Err   error // All the errors, joined, as returned by T.MGetWithErr.
+	}
+)
+
+// WithBatchSize sets the maximum number of ids passed to the MGet load
+// function per call. Larger misses are split into chunks loaded concurrently,
+// see WithLoadConcurrency. The failure of a chunk only fails its own ids.
+func WithBatchSize[V any](size int) TOption[V] {
+	return func(o *tOptions[V]) {
+		o.batchSize = size
+	}
+}
+
+// WithLoadConcurrency sets the maximum number of chunks loaded concurrently
+// by an MGet, defaulting to 4.
+func WithLoadConcurrency[V any](concurrency int) TOption[V] {
+	return func(o *tOptions[V]) {
+		o.loadConcurrency = concurrency
+	}
+}
+
+// WithLoadTimeout sets the deadline of the MGet load function calls. The
+// chunks not started by the deadline fail with context.DeadlineExceeded.
+func WithLoadTimeout[V any](timeout time.Duration) TOption[V] {
+	return func(o *tOptions[V]) {
+		o.loadTimeout = timeout
+	}
+}
+
+// WithCoalesceWindow delays the MGet loads by the window, so that the ids
+// missed meanwhile by the concurrent MGet calls of the same key and load
+// function are loaded by one call of the load function of the first of them.
+// The closures of one function literal count as one load function, so the
+// loaders differing only by what they capture, such as a tenant, must use
+// different keys, as their values are cached under the key anyway.
+func WithCoalesceWindow[V any](window time.Duration) TOption[V] {
+	return func(o *tOptions[V]) {
+		o.coalesceWindow = window
+	}
+}
+
+func newBatchResult[K constraints.Ordered, V any](size int) BatchResult[K, V] {
+	return BatchResult[K, V]{Items: make(map[K]ItemResult[V], size)}
+}
+
+// Values returns the values of the ids read from the caches or loaded,
+// including the loaded values which failed to encode.
+func (r BatchResult[K, V]) Values() map[K]V {
+	values := make(map[K]V, len(r.Items))
+	for id, item := range r.Items {
+		if item.hasValue() {
+			values[id] = item.Value
+		}
+	}
+
+	return values
+}
+
+// Failed returns the sorted ids which failed to decode or to load, to retry.
+func (r BatchResult[K, V]) Failed() []K {
+	var ids []K
+	for id, item := range r.Items {
+		if item.Status == StatusDecodeError || item.Status == StatusLoadError {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	return ids
+}
+
+// OK reports whether the item has a value, read from the caches or loaded
+// and cached. A StatusEncodeError item has a value, but is not OK.
+func (r ItemResult[V]) OK() bool {
+	return r.Status == StatusLocalHit || r.Status == StatusRemoteHit || r.Status == StatusLoaded
+}
+
+// hasValue reports whether the item has a value, even if not cached.
+func (r ItemResult[V]) hasValue() bool {
+	return r.OK() || r.Status == StatusEncodeError
+}
+
+func (s ItemStatus) String() string {
+	switch s {
+	case StatusMiss:
+		return "miss"
+	case StatusLocalHit:
+		return "local_hit"
+	case StatusRemoteHit:
+		return "remote_hit"
+	case StatusLoaded:
+		return "loaded"
+	case StatusNotFound:
+		return "not_found"
+	case StatusDecodeError:
+		return "decode_error"
+	case StatusLoadError:
+		return "load_error"
+	case StatusEncodeError:
+		return "encode_error"
+	default:
+		return "unknown"
+	}
+}
+
+// fail sets the status and the error of the id, and joins the error to Err.
+func (r *BatchResult[K, V]) fail(id K, status ItemStatus, err error) {
+	r.Items[id] = ItemResult[V]{Status: status, Err: err}
+	r.Err = errors.Join(r.Err, err)
+}
+
+// failLoad sets the status of the ids as StatusLoadError with the error of the
+// load function, and joins it to Err.
+func (r *BatchResult[K, V]) failLoad(ids []K, err error) {
+	for _, id := range ids {
+		r.Items[id] = ItemResult[V]{Status: StatusLoadError, Err: err}
+	}
+	r.Err = errors.Join(r.Err, fmt.Errorf("mQueryAndSetCache#fn(%v) error(%v)", ids, err))
+}
+
+// setMiss sets the ids which are neither cached nor loaded as missed.
+func (r *BatchResult[K, V]) setMiss(miss map[string]K) {
+	for _, id := range miss {
+		if _, ok := r.Items[id]; !ok {
+			r.Items[id] = ItemResult[V]{Status: StatusMiss}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+func TestMGetResults(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRdb()
+	c := New(WithName("batch"), WithRemote(remote.NewGoRedisV9Adapter(rdb)), WithLocal(localNew(freeCache)))
+	defer c.Close()
+	remoteC := New(WithName("batchRemote"), WithRemote(remote.NewGoRedisV9Adapter(rdb)))
+	defer remoteC.Close()
+
+	fn := func(_ context.Context, ids []int) (map[int]*object, error) {
+		ret := make(map[int]*object, len(ids))
+		for _, id := range ids {
+			if id != 4 {
+				ret[id] = &object{Str: "str", Num: id}
+			}
+		}
+		return ret, nil
+	}
+
+	t.Run("status", func(t *testing.T) {
+		cacheT := NewT[int, *object](c)
+		assert.NoError(t, cacheT.Set(ctx, "status", 1, &object{Str: "local", Num: 1}))
+		assert.NoError(t, NewT[int, *object](remoteC).Set(ctx, "status", 2, &object{Str: "remote", Num: 2}))
+
+		ret := cacheT.MGetResults(ctx, "status", []int{1, 2, 3, 4}, fn)
+		assert.NoError(t, ret.Err)
+		assert.Equal(t, StatusLocalHit, ret.Items[1].Status)
+		assert.Equal(t, "local", ret.Items[1].Value.Str)
+		assert.Equal(t, StatusRemoteHit, ret.Items[2].Status)
+		assert.Equal(t, "remote", ret.Items[2].Value.Str)
+		assert.Equal(t, StatusLoaded, ret.Items[3].Status)
+		assert.Equal(t, StatusNotFound, ret.Items[4].Status)
+		assert.Len(t, ret.Values(), 3)
+		assert.Empty(t, ret.Failed())
+
+		ret = cacheT.MGetResults(ctx, "status", []int{4}, fn)
+		assert.Equal(t, StatusNotFound, ret.Items[4].Status)
+		assert.False(t, ret.Items[4].OK())
+	})
+
+	t.Run("miss", func(t *testing.T) {
+		localC := New(WithName("batchLocal"), WithLocal(localNew(freeCache)))
+		defer localC.Close()
+
+		ret := NewT[int, *object](localC).MGetResults(ctx, "miss", []int{1, 2}, nil)
+		assert.NoError(t, ret.Err)
+		assert.Equal(t, StatusMiss, ret.Items[1].Status)
+		assert.Equal(t, StatusMiss, ret.Items[2].Status)
+		assert.NotNil(t, ret.Values())
+		assert.Empty(t, ret.Values())
+	})
+
+	t.Run("load error", func(t *testing.T) {
+		errLoad := errors.New("load error")
+		cacheT := NewT[int, *object](c)
+		assert.NoError(t, cacheT.Set(ctx, "loadErr", 1, &object{Str: "str", Num: 1}))
+
+		ret := cacheT.MGetResults(ctx, "loadErr", []int{3, 1, 2}, func(context.Context, []int) (map[int]*object, error) {
+			return nil, errLoad
+		})
+		assert.Error(t, ret.Err)
+		assert.Equal(t, StatusLocalHit, ret.Items[1].Status)
+		assert.Equal(t, StatusLoadError, ret.Items[2].Status)
+		assert.ErrorIs(t, ret.Items[2].Err, errLoad)
+		assert.Equal(t, []int{2, 3}, ret.Failed())
+		assert.Len(t, ret.Values(), 1)
+
+		values, err := cacheT.MGetWithErr(ctx, "loadErr", []int{1, 2}, func(context.Context, []int) (map[int]*object, error) {
+			return nil, errLoad
+		})
+		assert.Error(t, err)
+		assert.Len(t, values, 1)
+	})
+
+	t.Run("decode error", func(t *testing.T) {
+		decodeC := New(WithName("batchDecode"), WithRemote(remote.NewGoRedisV9Adapter(rdb)),
+			WithLocal(localNew(freeCache)), WithCodec(mockUnmarshalErr))
+		defer decodeC.Close()
+		cacheT := NewT[int, *object](decodeC)
+		assert.NoError(t, cacheT.Set(ctx, "decodeErr", 1, &object{Str: "str", Num: 1}))
+
+		ret := cacheT.MGetResults(ctx, "decodeErr", []int{1, 2}, fn)
+		assert.Error(t, ret.Err)
+		assert.Equal(t, StatusDecodeError, ret.Items[1].Status)
+		assert.Error(t, ret.Items[1].Err)
+		assert.Equal(t, StatusLoaded, ret.Items[2].Status)
+		assert.Equal(t, []int{1}, ret.Failed())
+	})
+
+	t.Run("encode error", func(t *testing.T) {
+		encodeC := New(WithName("batchEncode"), WithRemote(remote.NewGoRedisV9Adapter(rdb)),
+			WithLocal(localNew(freeCache)), WithCodec(mockMarshalErr))
+		defer encodeC.Close()
+		cacheT := NewT[int, *object](encodeC)
+
+		ret := cacheT.MGetResults(ctx, "encodeErr", []int{1}, fn)
+		assert.Error(t, ret.Err)
+		assert.Equal(t, StatusEncodeError, ret.Items[1].Status)
+		assert.Equal(t, 1, ret.Items[1].Value.Num)
+		assert.Error(t, ret.Items[1].Err)
+		assert.False(t, ret.Items[1].OK())
+		assert.Len(t, ret.Values(), 1)
+		assert.Empty(t, ret.Failed())
+		assert.False(t, encodeC.Exists(ctx, "encodeErr:1"))
+
+		loader := NewLoader(cacheT, "encodeErr", fn)
+		_, err := loader.Load(ctx, 2)
+		assert.Error(t, err)
+		values, err := loader.LoadMany(ctx, []int{3})
+		assert.Error(t, err)
+		assert.Len(t, values, 1)
+	})
+}
+
+func TestMGetChunks(t *testing.T) {
+	ctx := context.Background()
+	c := New(WithName("chunks"), WithRemote(remote.NewGoRedisV9Adapter(newRdb())), WithLocal(localNew(freeCache)))
+	defer c.Close()
+
+	errLoad := errors.New("load error")
+	var (
+		mu    sync.Mutex
+		calls [][]int
+	)
+	fn := func(_ context.Context, ids []int) (map[int]*object, error) {
+		sorted := append([]int(nil), ids...)
+		sort.Ints(sorted)
+		mu.Lock()
+		calls = append(calls, sorted)
+		mu.Unlock()
+		if sorted[0] == 3 {
+			return nil, errLoad
+		}
+		ret := make(map[int]*object, len(ids))
+		for _, id := range ids {
+			ret[id] = &object{Str: "str", Num: id}
+		}
+		return ret, nil
+	}
+
+	t.Run("partial failure", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithBatchSize[*object](2), WithLoadConcurrency[*object](2))
+		ret := cacheT.MGetResults(ctx, "chunks", []int{5, 4, 3, 2, 1}, fn)
+		assert.ElementsMatch(t, [][]int{{1, 2}, {3, 4}, {5}}, calls)
+		assert.Error(t, ret.Err)
+		assert.ErrorIs(t, ret.Items[3].Err, errLoad)
+		assert.Equal(t, []int{3, 4}, ret.Failed())
+		assert.Len(t, ret.Values(), 3)
+		assert.Equal(t, StatusLoaded, ret.Items[5].Status)
+
+		calls = nil
+		ret = cacheT.MGetResults(ctx, "chunks", []int{1, 2, 5}, fn)
+		assert.NoError(t, ret.Err)
+		assert.Empty(t, calls)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithBatchSize[*object](1), WithLoadConcurrency[*object](1),
+			WithLoadTimeout[*object](50*time.Millisecond))
+		ret := cacheT.MGetResults(ctx, "timeout", []int{1, 2}, func(ctx context.Context, ids []int) (map[int]*object, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		assert.Equal(t, []int{1, 2}, ret.Failed())
+		assert.ErrorIs(t, ret.Items[1].Err, context.DeadlineExceeded)
+		assert.ErrorIs(t, ret.Items[2].Err, context.DeadlineExceeded)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithBatchSize[*object](1))
+		ret := cacheT.MGetResults(ctx, "panic", []int{1, 2}, func(_ context.Context, ids []int) (map[int]*object, error) {
+			if ids[0] == 1 {
+				panic("test panic")
+			}
+			return map[int]*object{2: {Str: "str", Num: 2}}, nil
+		})
+		assert.Equal(t, []int{1}, ret.Failed())
+		assert.Equal(t, StatusLoaded, ret.Items[2].Status)
+	})
+}
+
+func TestItemStatusString(t *testing.T) {
+	assert.Equal(t, "local_hit", StatusLocalHit.String())
+	assert.Equal(t, "load_error", StatusLoadError.String())
+	assert.Equal(t, "encode_error", StatusEncodeError.String())
+	assert.Equal(t, "unknown", ItemStatus(100).String())
+}
@@ -1,19 +1,17 @@
 package cache
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"golang.org/x/sync/semaphore"
-	"golang.org/x/sync/singleflight"
-
 	"github.com/mgtv-tech/jetcache-go/encoding"
+	"github.com/mgtv-tech/jetcache-go/local"
 	"github.com/mgtv-tech/jetcache-go/logger"
+	"github.com/mgtv-tech/jetcache-go/stats"
 	"github.com/mgtv-tech/jetcache-go/util"
 )
 
@@ -21,14 +19,17 @@ const (
 	TypeLocal  = "local"
 	TypeRemote = "remote"
 	TypeBoth   = "both"
-
-	lockKeySuffix = "_#RL#"
 )
 
 var (
-	notFoundPlaceholder   = []byte("*")
-	ErrCacheMiss          = errors.New("cache: key is missing")
-	ErrRemoteLocalBothNil = errors.New("cache: both remote and local are nil")
+	ErrCacheMiss           = errors.New("cache: key is missing")
+	ErrRemoteLocalBothNil  = errors.New("cache: both remote and local are nil")
+	ErrRefreshTaskNotFound = errors.New("cache: refresh task not found")
+	ErrLocalUnsupported    = errors.New("cache: local cache does not support the operation")
+	ErrLocalShared         = errors.New("cache: local cache is shared with other caches")
+	ErrNotFoundDisabled    = errors.New("cache: errNotFound is not set")
+	ErrCounterUnsupported  = errors.New("cache: remote does not implement remote.Counter")
+	ErrCounterChecksum     = errors.New("cache: counters cannot be verified by WithChecksum")
 )
 
 type (
@@ -58,33 +59,177 @@ type (
 		CacheType() string
 		// Close closes the cache. This should be called when cache refreshing is
 		// enabled and no longer needed, or when it may lead to resource leaks.
+		// It waits for the pending writes of the WriteBehind mode to be flushed,
+		// at most for the timeout set by WithWriteFlushTimeout.
 		Close()
 	}
 
+	// The caches created by New also implement the optional interfaces below.
+	// Callers holding a Cache reach them by a type assertion.
+
+	// Shutdowner gracefully closes a cache.
+	Shutdowner interface {
+		// Shutdown gracefully closes the cache. It stops refreshing, cancels the
+		// context passed to in-flight loaders, waits for them to return and flushes
+		// pending writes and sync events. It returns ctx.Err() if ctx is done first,
+		// canceling the context passed to the Writer and dropping pending writes.
+		Shutdown(ctx context.Context) error
+	}
+
+	// Inspector exposes the config, statistics and raw values of a cache.
+	Inspector interface {
+		// Config returns a read-only view of the cache options.
+		Config() Config
+		// Stats returns the statistics of the cache since it was created.
+		Stats() stats.Stats
+		// Peek returns the raw value of the given key in each tier, without
+		// updating statistics or backfilling the local cache.
+		Peek(ctx context.Context, key string) ([]TierValue, error)
+	}
+
+	// RefreshController lists and controls the refresh tasks of a cache.
+	RefreshController interface {
+		// RefreshTasks returns the registered refresh tasks sorted by key.
+		RefreshTasks() []RefreshTaskInfo
+		// RefreshNow reloads the registered refresh key immediately, even if
+		// refreshing is paused, and returns the load error.
+		RefreshNow(ctx context.Context, key string) error
+		// CancelRefresh cancels the refresh task of the given key.
+		CancelRefresh(key string)
+		// PauseRefresh pauses the refreshing of all keys. The tasks stay registered.
+		PauseRefresh()
+		// ResumeRefresh resumes the refreshing paused by PauseRefresh.
+		ResumeRefresh()
+	}
+
+	// LocalController manages the local cache and applies the sync events of
+	// the other instances. ClearLocalCache, LocalCacheLen and RangeLocalCache
+	// fail with ErrLocalShared for a local shared by the caches of a Manager,
+	// see WithSharedLocal.
+	LocalController interface {
+		// ClearLocalCache clears the local cache and, with syncLocal, the local
+		// caches of the other instances through an EventTypeClearLocal event.
+		ClearLocalCache() error
+		// DeleteFromLocalCacheByPrefix deletes the local cached keys with the prefix and,
+		// with syncLocal, those of the other instances through an EventTypeDeletePrefix event.
+		DeleteFromLocalCacheByPrefix(prefix string) error
+		// LocalCacheLen returns the number of local cached keys.
+		LocalCacheLen() (int, error)
+		// RangeLocalCache calls fn for each local cached key and its raw value until fn returns false.
+		RangeLocalCache(fn func(key string, data []byte) bool) error
+		// HandleEvent applies a sync event received from another cache instance
+		// to the local cache. Events sent by this instance are ignored.
+		HandleEvent(event *Event)
+	}
+
+	// Invalidator deletes keys without calling the Writer.
+	Invalidator interface {
+		// DeleteWithDelay deletes the key now and again after the delay, for
+		// the delayed double delete around data source updates. The follow-up
+		// deletes and the retries of the failed ones are run by Close.
+		DeleteWithDelay(ctx context.Context, key string, delay time.Duration) error
+		// Invalidate deletes the keys without calling the Writer, for invalidations
+		// driven by the data source such as change data capture. It sends one
+		// sync event for the keys deleted from the remote cache.
+		Invalidate(ctx context.Context, keys ...string) error
+	}
+
+	// NotFoundCache caches keys as known-absent.
+	NotFoundCache interface {
+		// SetNotFound caches the keys as known-absent for the ttl, or the notFoundExpiry
+		// if the ttl is 0, so that Get and Once return errNotFound without loading them.
+		SetNotFound(ctx context.Context, ttl time.Duration, keys ...string) error
+		// IsNotFoundCached reports whether the key is cached as known-absent.
+		IsNotFoundCached(ctx context.Context, key string) (bool, error)
+		// DeleteNotFound deletes the keys cached as known-absent, keeping the cached values.
+		DeleteNotFound(ctx context.Context, keys ...string) error
+	}
+
+	// Counter atomically updates integer counters.
+	Counter interface {
+		// Incr atomically adds delta to the counter of the key and returns the new
+		// value. A missing key is created expiring after the ttl, or the remoteExpiry
+		// if the ttl is 0. It requires a remote implementing remote.Counter without
+		// WithChecksum, or no remote.
+		Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+		// Decr atomically subtracts delta from the counter of the key, see Incr.
+		Decr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	}
+
+	// TierValue is the raw value of a key in one cache tier.
+	TierValue struct {
+		Tier        string // TypeLocal or TypeRemote.
+		Found       bool
+		Placeholder bool   // The value is the placeholder cached for errNotFound.
+		Raw         []byte // Including the checksum trailer in the remote tier, see WithChecksum.
+		Value       []byte // Raw without the checksum trailer, nil if the trailer does not match.
+	}
+
 	jetCache struct {
-		sync.Mutex
 		Options
-		group          singleflight.Group
+		counter        *stats.Stats // cumulative statistics, see Stats.
+		inflight       flightGroup  // in-flight reads and loads by key, see Once and T.MGet.
+		writes         *writeQueue  // pending writes of the WriteBehind mode.
+		deletesMu      sync.Mutex
+		deletes        map[*delayedDelete]struct{} // scheduled deletes, see DeleteWithDelay.
+		deletesClosed  bool
+		deletesWg      sync.WaitGroup // tracks the running scheduled deletes.
+		localMu        sync.Mutex     // serializes the read-modify-writes of the local only caches, see Incr and T.Update.
 		safeRand       *util.SafeRand
 		refreshTaskMap sync.Map
+		refreshMu      sync.Mutex
+		refreshQueue   refreshQueue  // min-heap of scheduled refresh tasks, guarded by refreshMu.
+		refreshWakeCh  chan struct{} // wakes up the scheduler when an earlier task is scheduled.
+		refreshOnce    sync.Once
+		refreshPaused  atomic.Bool
+		refreshWg      sync.WaitGroup // tracks the scheduler, in-flight loaders and pending timers.
+		timerMu        sync.Mutex
+		timers         map[*time.Timer]struct{}
+		tiersMu        sync.RWMutex
+		tiers          []localTier // decoded value tiers of the T wrapping the cache.
+		handlerWg      sync.WaitGroup
 		eventCh        chan *Event
+		ctx            context.Context // root context of refresh loaders, canceled on Close.
+		cancelFunc     context.CancelFunc
 		stopChan       chan struct{}
+		stopOnce       sync.Once
 	}
 )
 
+var (
+	_ Cache             = (*jetCache)(nil)
+	_ Shutdowner        = (*jetCache)(nil)
+	_ Inspector         = (*jetCache)(nil)
+	_ RefreshController = (*jetCache)(nil)
+	_ LocalController   = (*jetCache)(nil)
+	_ Invalidator       = (*jetCache)(nil)
+	_ NotFoundCache     = (*jetCache)(nil)
+	_ Counter           = (*jetCache)(nil)
+)
+
 func New(opts ...Option) Cache {
 	o := newOptions(opts...)
 	cache := &jetCache{
-		Options:  o,
-		safeRand: util.NewSafeRand(),
-		eventCh:  make(chan *Event, o.eventChBufSize),
-		stopChan: make(chan struct{}),
+		Options:       o,
+		counter:       &stats.Stats{Name: o.name},
+		safeRand:      util.NewSafeRand(),
+		refreshWakeCh: make(chan struct{}, 1),
+		timers:        make(map[*time.Timer]struct{}),
+		deletes:       make(map[*delayedDelete]struct{}),
+		eventCh:       make(chan *Event, o.eventChBufSize),
+		stopChan:      make(chan struct{}),
 	}
+	cache.ctx, cache.cancelFunc = context.WithCancel(context.Background())
 
-	if cache.refreshDuration > 0 {
-		cache.tick()
+	if o.writer != nil && o.writeMode == WriteBehind {
+		cache.writes = newWriteQueue(o)
 	}
 
+	if !o.statsDisabled {
+		cache.statsHandler = stats.NewHandles(false, o.statsHandler, cache.counter)
+	}
+	cache.reportLocalMetrics()
+
 	if cache.isSyncLocal() {
 		cache.startEventHandler()
 	}
@@ -92,11 +237,58 @@ func New(opts ...Option) Cache {
 	return cache
 }
 
+// reportLocalMetrics hands the metrics of a local cache implementing
+// local.MetricsReporter to the stats handlers implementing stats.LocalMetricsHandler.
+// The metrics of a shared local are not reported, as they cover all the caches
+// sharing it.
+func (c *jetCache) reportLocalMetrics() {
+	reporter, ok := c.local.(local.MetricsReporter)
+	if !ok || c.sharedLocal {
+		return
+	}
+
+	fn := func() stats.LocalMetrics {
+		m := reporter.Metrics()
+		return stats.LocalMetrics{
+			Entries:     m.Entries,
+			Bytes:       m.Bytes,
+			Capacity:    m.Capacity,
+			Evictions:   m.Evictions,
+			Expirations: m.Expirations,
+			Overwrites:  m.Overwrites,
+			Rejections:  m.Rejections,
+		}
+	}
+	c.counter.SetLocalMetrics(fn)
+	if h, ok := c.statsHandler.(stats.LocalMetricsHandler); ok {
+		h.SetLocalMetrics(fn)
+	}
+}
+
 func (c *jetCache) Set(ctx context.Context, key string, opts ...ItemOption) error {
-	_, ok, err := c.set(newItemOptions(ctx, key, opts...))
+	item := newItemOptions(ctx, key, opts...)
+	write := c.writer != nil && item.do == nil
+	if write && c.writeMode == WriteThrough {
+		if err := c.write(item, nil); err != nil {
+			return err
+		}
+	}
+
+	b, ok, err := c.set(item)
 	if ok {
 		c.send(EventTypeSet, key)
 	}
+	if err != nil || !write || c.writeMode != WriteBehind {
+		return err
+	}
+
+	// The value is in the cache but will not reach the data source, so
+	// evict it rather than serve a value the data source never had.
+	if err = c.write(item, b); err != nil {
+		if e := c.del(item.Context(), key); e != nil {
+			logger.Error("Set#c.del(%s) error(%v)", key, e)
+		}
+	}
 
 	return err
 }
@@ -108,10 +300,11 @@ func (c *jetCache) set(item *item) ([]byte, bool, error) {
 	}
 
 	if c.IsNotFound(err) {
-		if e := c.setNotFound(item.Context(), item.key, item.skipLocal); e != nil {
+		c.delLocalTiers(item.key)
+		if e := c.setNotFound(item.Context(), item.key, item.skipLocal, item.notFoundTTL); e != nil {
 			logger.Error("setNotFound(%s) error(%v)", item.key, err)
 		}
-		return notFoundPlaceholder, true, nil
+		return c.placeholder(), true, nil
 	} else if err != nil {
 		c.statsHandler.IncrQueryFail(err)
 		return nil, false, err
@@ -122,6 +315,7 @@ func (c *jetCache) set(item *item) ([]byte, bool, error) {
 		return nil, false, err
 	}
 
+	c.delLocalTiers(item.key)
 	if c.local != nil && !item.skipLocal {
 		c.local.Set(item.key, b)
 	}
@@ -138,15 +332,16 @@ func (c *jetCache) set(item *item) ([]byte, bool, error) {
 		return b, true, nil
 	}
 
+	sealed := c.seal(item.key, b)
 	if item.setXX {
-		_, err := c.remote.SetXX(item.Context(), item.key, b, ttl)
+		_, err := c.remote.SetXX(item.Context(), item.key, sealed, ttl)
 		return b, true, err
 	}
 	if item.setNX {
-		_, err := c.remote.SetNX(item.Context(), item.key, b, ttl)
+		_, err := c.remote.SetNX(item.Context(), item.key, sealed, ttl)
 		return b, true, err
 	}
-	return b, true, c.remote.SetEX(item.Context(), item.key, b, ttl)
+	return b, true, c.remote.SetEX(item.Context(), item.key, sealed, ttl)
 }
 
 func (c *jetCache) Exists(ctx context.Context, key string) bool {
@@ -177,7 +372,7 @@ func (c *jetCache) getBytes(ctx context.Context, key string, skipLocal bool) ([]
 		if ok {
 			c.statsHandler.IncrHit()
 			c.statsHandler.IncrLocalHit()
-			if bytes.Compare(b, notFoundPlaceholder) == 0 {
+			if c.isNotFoundPlaceholder(b) {
 				return nil, c.errNotFound
 			}
 			return b, nil
@@ -203,11 +398,17 @@ func (c *jetCache) getBytes(ctx context.Context, key string, skipLocal bool) ([]
 		return nil, err
 	}
 
+	b, ok := c.verify(ctx, key, util.Bytes(s))
+	if !ok {
+		c.statsHandler.IncrMiss()
+		c.statsHandler.IncrRemoteMiss()
+		return nil, ErrCacheMiss
+	}
+
 	c.statsHandler.IncrHit()
 	c.statsHandler.IncrRemoteHit()
 
-	b := util.Bytes(s)
-	if bytes.Compare(b, notFoundPlaceholder) == 0 {
+	if c.isNotFoundPlaceholder(b) {
 		return nil, c.errNotFound
 	}
 
@@ -219,32 +420,38 @@ func (c *jetCache) getBytes(ctx context.Context, key string, skipLocal bool) ([]
 }
 
 func (c *jetCache) Once(ctx context.Context, key string, opts ...ItemOption) error {
+	_, err := c.once(ctx, key, opts...)
+	return err
+}
+
+// once is Once returning the bytes the value was decoded from.
+func (c *jetCache) once(ctx context.Context, key string, opts ...ItemOption) ([]byte, error) {
 	item := newItemOptions(ctx, key, opts...)
 
 	c.addOrUpdateRefreshTask(item)
 
 	b, cached, err := c.getSetItemBytesOnce(item)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if bytes.Compare(b, notFoundPlaceholder) == 0 {
-		return c.errNotFound
+	if c.isNotFoundPlaceholder(b) {
+		return nil, c.errNotFound
 	}
 
 	if item.value == nil || len(b) == 0 {
-		return nil
+		return b, nil
 	}
 
 	if err := c.Unmarshal(b, item.value); err != nil {
 		if cached {
-			_ = c.Delete(ctx, item.key)
-			return c.Once(ctx, key, opts...)
+			_ = c.del(ctx, item.key)
+			return c.once(ctx, key, opts...)
 		}
-		return err
+		return nil, err
 	}
 
-	return nil
+	return b, nil
 }
 
 func (c *jetCache) getSetItemBytesOnce(item *item) (b []byte, cached bool, err error) {
@@ -253,40 +460,44 @@ func (c *jetCache) getSetItemBytesOnce(item *item) (b []byte, cached bool, err e
 		if ok {
 			c.statsHandler.IncrHit()
 			c.statsHandler.IncrLocalHit()
-			if bytes.Compare(b, notFoundPlaceholder) == 0 {
+			if c.isNotFoundPlaceholder(b) {
 				return nil, true, c.errNotFound
 			}
 			return b, true, nil
 		}
 	}
 
-	v, err, _ := c.group.Do(item.key, func() (any, error) {
+	return c.inflight.do(item.key, func() ([]byte, bool, error) {
 		b, err := c.getBytes(item.Context(), item.key, item.skipLocal)
 		if err == nil {
-			cached = true
-			return b, nil
+			return b, true, nil
 		} else if errors.Is(err, c.errNotFound) {
-			cached = true
-			return nil, c.errNotFound
+			return nil, true, c.errNotFound
 		}
 
 		b, ok, err := c.set(item)
 		if ok {
 			c.send(EventTypeSetByOnce, item.key)
-			return b, nil
+			return b, false, nil
 		}
 
-		return nil, err
+		return nil, false, err
 	})
+}
 
-	if err != nil {
-		return nil, false, err
+func (c *jetCache) Delete(ctx context.Context, key string) error {
+	if c.writer != nil {
+		if err := c.writeDelete(ctx, key); err != nil {
+			return err
+		}
 	}
 
-	return v.([]byte), cached, nil
+	return c.del(ctx, key)
 }
 
-func (c *jetCache) Delete(ctx context.Context, key string) error {
+// del deletes the key from the cache tiers, and sends the sync event.
+func (c *jetCache) del(ctx context.Context, key string) error {
+	c.delLocalTiers(key)
 	if c.local != nil {
 		c.local.Del(key)
 	}
@@ -306,35 +517,154 @@ func (c *jetCache) Delete(ctx context.Context, key string) error {
 	return err
 }
 
+func (c *jetCache) Invalidate(ctx context.Context, keys ...string) error {
+	if c.local == nil && c.remote == nil {
+		return ErrRemoteLocalBothNil
+	}
+
+	var errs error
+	deleted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		c.delLocalTiers(key)
+		if c.local != nil {
+			c.local.Del(key)
+		}
+		if c.remote == nil {
+			continue
+		}
+		if _, err := c.remote.Del(ctx, key); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("Invalidate#c.remote.Del(%s) error(%v)", key, err))
+			continue
+		}
+		deleted = append(deleted, key)
+	}
+	if len(deleted) > 0 {
+		c.send(EventTypeDelete, deleted...)
+	}
+
+	return errs
+}
+
 func (c *jetCache) DeleteFromLocalCache(key string) {
+	c.delLocalTiers(key)
 	if c.local != nil {
 		c.local.Del(key)
 	}
 }
 
-func (c *jetCache) IsNotFound(err error) bool {
-	if err == nil {
-		return false
+func (c *jetCache) ClearLocalCache() error {
+	if err := c.clearLocal(); err != nil {
+		return err
 	}
+	c.send(EventTypeClearLocal)
 
-	return errors.Is(err, c.errNotFound)
+	return nil
 }
 
-func (c *jetCache) setNotFound(ctx context.Context, key string, skipLocal bool) error {
-	if c.local != nil && !skipLocal {
-		c.local.Set(key, notFoundPlaceholder)
+func (c *jetCache) DeleteFromLocalCacheByPrefix(prefix string) error {
+	if err := c.deleteLocalPrefix(prefix); err != nil {
+		return err
 	}
+	c.send(EventTypeDeletePrefix, prefix)
 
-	if c.remote == nil {
-		if c.local == nil {
-			return ErrRemoteLocalBothNil
+	return nil
+}
+
+func (c *jetCache) LocalCacheLen() (int, error) {
+	if c.local == nil {
+		return 0, nil
+	}
+	if c.sharedLocal {
+		return 0, ErrLocalShared
+	}
+
+	l, ok := c.local.(local.Lener)
+	if !ok {
+		return 0, ErrLocalUnsupported
+	}
+
+	return l.Len(), nil
+}
+
+func (c *jetCache) RangeLocalCache(fn func(key string, data []byte) bool) error {
+	if c.local == nil {
+		return nil
+	}
+	if c.sharedLocal {
+		return ErrLocalShared
+	}
+
+	r, ok := c.local.(local.Ranger)
+	if !ok {
+		return ErrLocalUnsupported
+	}
+	r.Range(fn)
+
+	return nil
+}
+
+func (c *jetCache) HandleEvent(event *Event) {
+	if event == nil || event.SourceID == c.sourceID || (c.local == nil && !c.hasLocalTiers()) {
+		return
+	}
+
+	switch event.EventType {
+	case EventTypeClearLocal:
+		if err := c.clearLocal(); err != nil {
+			logger.Error("HandleEvent clearLocal error(%v)", err)
+		}
+	case EventTypeDeletePrefix:
+		for _, prefix := range event.Keys {
+			if err := c.deleteLocalPrefix(prefix); err != nil {
+				logger.Error("HandleEvent deleteLocalPrefix(%s) error(%v)", prefix, err)
+			}
 		}
+	default:
+		for _, key := range event.Keys {
+			c.DeleteFromLocalCache(key)
+		}
+	}
+}
+
+func (c *jetCache) clearLocal() error {
+	c.clearLocalTiers()
+	if c.local == nil {
 		return nil
 	}
+	if c.sharedLocal {
+		return ErrLocalShared
+	}
 
-	ttl := c.notFoundExpiry + time.Duration(c.safeRand.Int63n(int64(c.offset)))
+	l, ok := c.local.(local.Clearer)
+	if !ok {
+		return ErrLocalUnsupported
+	}
+	l.Clear()
 
-	return c.remote.SetEX(ctx, key, notFoundPlaceholder, ttl)
+	return nil
+}
+
+func (c *jetCache) deleteLocalPrefix(prefix string) error {
+	c.delLocalTiersPrefix(prefix)
+	if c.local == nil {
+		return nil
+	}
+
+	l, ok := c.local.(local.PrefixDeleter)
+	if !ok {
+		return ErrLocalUnsupported
+	}
+	l.DelPrefix(prefix)
+
+	return nil
+}
+
+func (c *jetCache) IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return errors.Is(err, c.errNotFound)
 }
 
 func (c *jetCache) Marshal(val any) ([]byte, error) {
@@ -372,8 +702,53 @@ func (c *jetCache) Unmarshal(b []byte, val any) error {
 }
 
 func (c *jetCache) Close() {
+	if c.writes != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), c.writeFlushTimeout)
+		_ = c.writes.close(ctx)
+		cancel()
+	}
+	_ = c.flushDeletes(context.Background())
 	c.stopRefresh()
-	close(c.stopChan)
+	c.stopOnce.Do(func() {
+		close(c.stopChan)
+	})
+}
+
+func (c *jetCache) Shutdown(ctx context.Context) error {
+	c.stopRefresh()
+	if c.writes != nil {
+		if err := c.writes.close(ctx); err != nil {
+			return err
+		}
+	}
+	if err := c.flushDeletes(ctx); err != nil {
+		return err
+	}
+	if err := wait(ctx, &c.refreshWg); err != nil {
+		return err
+	}
+
+	c.stopOnce.Do(func() {
+		close(c.stopChan)
+	})
+
+	return wait(ctx, &c.handlerWg)
+}
+
+// wait waits for wg, or returns ctx.Err() if ctx is done first.
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (c *jetCache) TaskSize() (size int) {
@@ -393,142 +768,67 @@ func (c *jetCache) CacheType() string {
 	return TypeLocal
 }
 
-func (c *jetCache) addOrUpdateRefreshTask(item *item) {
-	if c.refreshDuration <= 0 || !item.refresh {
-		return
-	}
-
-	if ins, ok := c.refreshTaskMap.Load(item.key); ok {
-		ins.(*refreshTask).lastAccessTime = time.Now()
-	} else if ins, loaded := c.refreshTaskMap.LoadOrStore(item.key, item.toRefreshTask()); loaded {
-		ins.(*refreshTask).lastAccessTime = time.Now()
+func (c *jetCache) Config() Config {
+	return Config{
+		Name:                       c.name,
+		CacheType:                  c.CacheType(),
+		Codec:                      c.codec,
+		RemoteExpiry:               c.remoteExpiry,
+		NotFoundExpiry:             c.notFoundExpiry,
+		Offset:                     c.offset,
+		RefreshDuration:            c.refreshDuration,
+		StopRefreshAfterLastAccess: c.stopRefreshAfterLastAccess,
+		RefreshConcurrency:         c.refreshConcurrency,
+		StatsDisabled:              c.statsDisabled,
+		SourceID:                   c.sourceID,
+		SyncLocal:                  c.syncLocal,
+		EventChBufSize:             c.eventChBufSize,
+		Separator:                  c.separator,
+		ErrNotFound:                c.errNotFound,
 	}
 }
 
-func (c *jetCache) cancel(key any) {
-	c.refreshTaskMap.Delete(key)
+func (c *jetCache) Stats() stats.Stats {
+	return c.counter.Snapshot()
 }
 
-func (c *jetCache) stopRefresh() {
-	c.refreshTaskMap.Range(func(key, val any) bool {
-		c.cancel(key)
-		return true
-	})
-}
-
-func (c *jetCache) tick() {
-	go util.WithRecover(func() {
-		var (
-			ticker = time.NewTicker(c.refreshDuration)
-			sem    = semaphore.NewWeighted(int64(c.refreshConcurrency))
-		)
-		for {
-			select {
-			case <-ticker.C:
-				c.Lock()
-				// now is placed outside the Range to ensure that stopRefreshAfterLastAccess
-				// does not time out under concurrent queuing.
-				var now = time.Now()
-				c.refreshTaskMap.Range(func(key, val any) bool {
-					task := val.(*refreshTask)
-					if c.stopRefreshAfterLastAccess > 0 {
-						if task.lastAccessTime.Add(c.stopRefreshAfterLastAccess).Before(now) {
-							logger.Debug("cancel refresh key: %s", key)
-							c.cancel(key)
-						} else {
-							if err := sem.Acquire(context.Background(), 1); err != nil {
-								logger.Error("tick#sem.Acquire error(%v)", err)
-								return true
-							}
-
-							go util.WithRecover(func() {
-								defer sem.Release(1)
-
-								logger.Debug("start refresh key: %s", key)
-								if c.remote != nil {
-									c.externalLoad(context.Background(), task, now)
-									return
-								}
-								c.load(context.Background(), task)
-							})
-						}
-					}
-					return true
-				})
-				c.Unlock()
-			case <-c.stopChan:
-				return
-			}
-		}
-	})
-}
-
-func (c *jetCache) externalLoad(ctx context.Context, task *refreshTask, now time.Time) {
-	var (
-		lockKey    = fmt.Sprintf("%s%s", task.key, lockKeySuffix)
-		shouldLoad bool
-	)
-	_, err := c.remote.Get(ctx, lockKey)
-	if errors.Is(err, c.remote.Nil()) {
-		shouldLoad = true
-	} else if err != nil {
-		logger.Error("externalLoad#c.remote.Get(%s) error(%v)", lockKey, err)
-		return
-	}
-
-	if !shouldLoad {
-		if c.local != nil {
-			c.refreshLocal(ctx, task)
-		}
-		return
+func (c *jetCache) Peek(ctx context.Context, key string) ([]TierValue, error) {
+	var values []TierValue
+	if c.local != nil {
+		b, ok := c.local.Get(key)
+		values = append(values, TierValue{
+			Tier:        TypeLocal,
+			Found:       ok,
+			Placeholder: ok && c.isNotFoundPlaceholder(b),
+			Raw:         b,
+			Value:       b,
+		})
 	}
 
-	// issues: https://github.com/mgtv-tech/jetcache-go/issues/36
-	lockTimeout := c.refreshDuration - 10*time.Millisecond
-	ok, err := c.remote.SetNX(ctx, lockKey, strconv.FormatInt(now.Unix(), 10), lockTimeout)
-	if err != nil {
-		logger.Error("externalLoad#c.remote.setNX(%s) error(%v)", lockKey, err)
-		return
-	}
-	if ok {
-		_, ok, err := c.set(newItemOptions(ctx, task.key, TTL(task.ttl), Do(task.do), SetXX(task.setXX),
-			SetNX(task.setNX), SkipLocal(task.skipLocal)))
-		if ok {
-			c.send(EventTypeSetByRefresh, task.key)
+	if c.remote != nil {
+		s, err := c.remote.Get(ctx, key)
+		if err != nil && !errors.Is(err, c.remote.Nil()) {
+			return values, err
 		}
-		if err != nil {
-			logger.Error("externalLoad#c.Set(%s) error(%v)", task.key, err)
-			return
+		var b, unsealed []byte
+		if err == nil {
+			b = []byte(s)
+			unsealed, _ = c.unseal(key, b)
 		}
-	} else if c.local != nil {
-		// If this goroutine fails to acquire the concurrent lock, it needs to wait briefly (delay) to trigger a refresh.
-		// This way, it can directly fetch the origin result from Redis and refresh it locally.
-		// The maximum concurrency here refers to the number of web machine instances, and the probability of
-		// concurrent processing is actually not high. time.AfterFunc can be understood as a fallback mechanism to
-		// reduce cache inconsistency time.
-		time.AfterFunc(c.refreshDuration/5, func() {
-			go util.WithRecover(func() {
-				c.refreshLocal(context.Background(), task)
-			})
+		values = append(values, TierValue{
+			Tier:        TypeRemote,
+			Found:       err == nil,
+			Placeholder: err == nil && c.isNotFoundPlaceholder(unsealed),
+			Raw:         b,
+			Value:       unsealed,
 		})
 	}
-}
 
-func (c *jetCache) load(ctx context.Context, task *refreshTask) {
-	_, _, err := c.set(newItemOptions(ctx, task.key, TTL(task.ttl), Do(task.do), SetXX(task.setXX),
-		SetNX(task.setNX), SkipLocal(task.skipLocal)))
-	if err != nil {
-		logger.Error("load#c.Set(%s) error(%v)", task.key, err)
+	if values == nil {
+		return nil, ErrRemoteLocalBothNil
 	}
-}
 
-func (c *jetCache) refreshLocal(ctx context.Context, task *refreshTask) {
-	val, err := c.remote.Get(ctx, task.key)
-	if err != nil {
-		logger.Error("refreshLocal#c.remote.Get(%s) error(%v)", task.key, err)
-		return
-	}
-	c.local.Set(task.key, util.Bytes(val))
+	return values, nil
 }
 
 // isSyncLocal is
@@ -565,19 +865,42 @@ func (c *jetCache) startEventHandler() {
 		return
 	}
 
+	c.handlerWg.Add(1)
 	go util.WithRecover(func() {
+		defer c.handlerWg.Done()
+
 		for {
 			select {
 			case e, ok := <-c.eventCh:
 				if !ok {
 					continue
 				}
-				util.WithRecover(func() {
-					c.eventHandler(e)
-				})
+				c.publish(e)
 			case <-c.stopChan:
+				c.flushEvents()
+				return
+			}
+		}
+	})
+}
+
+// flushEvents publishes the events still buffered in eventCh.
+func (c *jetCache) flushEvents() {
+	for {
+		select {
+		case e, ok := <-c.eventCh:
+			if !ok {
 				return
 			}
+			c.publish(e)
+		default:
+			return
 		}
+	}
+}
+
+func (c *jetCache) publish(e *Event) {
+	util.WithRecover(func() {
+		c.eventHandler(e)
 	})
 }
@@ -93,7 +93,7 @@ var _ = Describe("Cache", func() {
 			}))
 			Expect(err).To(Equal(ErrRemoteLocalBothNil))
 
-			err = nilCache.setNotFound(ctx, "key", false)
+			err = nilCache.setNotFound(ctx, "key", false, 0)
 			Expect(err).To(Equal(ErrRemoteLocalBothNil))
 		})
 
@@ -163,6 +163,72 @@ var _ = Describe("Cache", func() {
 			}
 		})
 
+		It("Peeks values and reports stats", func() {
+			err := cache.Set(ctx, key, Value("value"), TTL(time.Hour))
+			Expect(err).NotTo(HaveOccurred())
+
+			values, err := cache.(Inspector).Peek(ctx, key)
+			Expect(err).NotTo(HaveOccurred())
+			for _, v := range values {
+				Expect(v.Found).To(BeTrue())
+				Expect(v.Placeholder).To(BeFalse())
+				Expect(string(v.Raw)).To(Equal("value"))
+			}
+			Expect(len(values)).To(Equal(map[string]int{TypeLocal: 1, TypeRemote: 1, TypeBoth: 2}[cache.CacheType()]))
+
+			before := cache.(Inspector).Stats()
+			Expect(cache.Get(ctx, key, nil)).To(Succeed())
+			Expect(cache.(Inspector).Stats().Hit).To(Equal(before.Hit + 1))
+			if cache.CacheType() != TypeRemote {
+				Expect(cache.(Inspector).Stats().Local.Entries).To(BeNumerically(">", 0))
+				Expect(cache.(Inspector).Stats().Local.Capacity).To(BeNumerically(">", 0))
+			}
+
+			Expect(cache.(Inspector).Config().CacheType).To(Equal(cache.CacheType()))
+			Expect(cache.(Inspector).Config().Name).NotTo(BeEmpty())
+		})
+
+		It("Ranges, deletes by prefix and clears local cache", func() {
+			for _, k := range []string{"product:1", "product:2", "user:1"} {
+				Expect(cache.Set(ctx, k, Value(k))).To(Succeed())
+			}
+
+			n, err := cache.(LocalController).LocalCacheLen()
+			Expect(err).NotTo(HaveOccurred())
+			if cache.CacheType() == TypeRemote {
+				Expect(n).To(Equal(0))
+				Expect(cache.(LocalController).ClearLocalCache()).To(Succeed())
+				return
+			}
+			Expect(n).To(Equal(3))
+
+			keys := make(map[string]string)
+			err = cache.(LocalController).RangeLocalCache(func(key string, data []byte) bool {
+				keys[key] = string(data)
+				return true
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(keys).To(HaveKeyWithValue("user:1", "user:1"))
+
+			Expect(cache.(LocalController).DeleteFromLocalCacheByPrefix("product:")).To(Succeed())
+			n, _ = cache.(LocalController).LocalCacheLen()
+			Expect(n).To(Equal(1))
+
+			cache.(LocalController).HandleEvent(&Event{SourceID: "other", EventType: EventTypeClearLocal})
+			n, _ = cache.(LocalController).LocalCacheLen()
+			Expect(n).To(Equal(0))
+
+			Expect(cache.Set(ctx, "product:3", Value("v"))).To(Succeed())
+			cache.(LocalController).HandleEvent(&Event{SourceID: "other", EventType: EventTypeDeletePrefix, Keys: []string{"product:"}})
+			n, _ = cache.(LocalController).LocalCacheLen()
+			Expect(n).To(Equal(0))
+
+			Expect(cache.Set(ctx, "user:2", Value("v"))).To(Succeed())
+			Expect(cache.(LocalController).ClearLocalCache()).To(Succeed())
+			n, _ = cache.(LocalController).LocalCacheLen()
+			Expect(n).To(Equal(0))
+		})
+
 		It("Sets string as is", func() {
 			value := "str_value"
 
@@ -311,7 +377,7 @@ var _ = Describe("Cache", func() {
 							return map[int]*object{1: {Str: "str1", Num: 1}, 2: {Str: "str2", Num: 2}}, nil
 						})
 					Expect(ret).To(Equal(map[int]*object{1: {Str: "str1", Num: 1}, 2: {Str: "str2", Num: 2}}))
-					// 2nd cache hit placeholder "*", then return miss
+					// 2nd marshal errors are not cached, then return miss
 					ret = cacheT.MGet(context.Background(), "key", ids, nil)
 					Expect(ret).To(Equal(map[int]*object{}))
 				}
@@ -329,7 +395,7 @@ var _ = Describe("Cache", func() {
 							return map[int]*object{1: {Str: "str1", Num: 1}, 2: {Str: "str2", Num: 2}}, nil
 						})
 					Expect(ret).To(Equal(map[int]*object{1: {Str: "str1", Num: 1}, 2: {Str: "str2", Num: 2}}))
-					// 2nd cache hit placeholder "*", then return miss
+					// 2nd marshal errors are not cached, then return miss
 					ret = cacheT.MGet(context.Background(), "key", ids, nil)
 					Expect(ret).To(Equal(map[int]*object{}))
 				}
@@ -347,7 +413,7 @@ var _ = Describe("Cache", func() {
 							return map[int]*object{1: {Str: "str1", Num: 1}, 2: {Str: "str2", Num: 2}}, nil
 						})
 					Expect(ret).To(Equal(map[int]*object{1: {Str: "str1", Num: 1}, 2: {Str: "str2", Num: 2}}))
-					// 2nd cache hit placeholder "*", then return miss
+					// 2nd cache miss, then return miss
 					ret = cacheT.MGet(context.Background(), "key", ids, nil)
 					Expect(ret).To(Equal(map[int]*object{}))
 				}
@@ -389,7 +455,7 @@ var _ = Describe("Cache", func() {
 				if cache.CacheType() == TypeRemote || cache.CacheType() == TypeBoth {
 					val, err := rdb.Get(context.Background(), key).Result()
 					Expect(err).To(BeNil())
-					Expect(val).To(Equal(string(notFoundPlaceholder)))
+					Expect(val).To(Equal(string(legacyPlaceholder)))
 				}
 
 				_ = cache.Set(ctx, key, Value(value), Do(do))
@@ -531,6 +597,64 @@ var _ = Describe("Cache", func() {
 				Expect(value).To(Equal("V1"))
 			})
 
+			It("shutdown waits for in-flight refresh", func() {
+				var (
+					key       = fmt.Sprintf("%s:%s", cache.CacheType(), "K1")
+					started   = make(chan struct{})
+					once      sync.Once
+					callCount int64
+					canceled  int32
+					value     string
+				)
+				err := cache.Once(ctx, key, Value(&value), TTL(time.Minute), Refresh(true),
+					Do(func(ctx context.Context) (any, error) {
+						if atomic.AddInt64(&callCount, 1) == 1 {
+							return "V1", nil
+						}
+						once.Do(func() { close(started) })
+						<-ctx.Done()
+						atomic.StoreInt32(&canceled, 1)
+						return nil, ctx.Err()
+					}))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(value).To(Equal("V1"))
+
+				Eventually(started, 2*refreshDuration).Should(BeClosed())
+				Expect(cache.(Shutdowner).Shutdown(context.Background())).To(Succeed())
+				Expect(atomic.LoadInt32(&canceled)).To(Equal(int32(1)))
+				Expect(cache.TaskSize()).To(Equal(0))
+
+				cache.Close()
+			})
+
+			It("shutdown returns when ctx is done", func() {
+				var (
+					key       = fmt.Sprintf("%s:%s", cache.CacheType(), "K1")
+					started   = make(chan struct{})
+					release   = make(chan struct{})
+					once      sync.Once
+					callCount int64
+					value     string
+				)
+				defer close(release)
+
+				err := cache.Once(ctx, key, Value(&value), TTL(time.Minute), Refresh(true),
+					Do(func(context.Context) (any, error) {
+						if atomic.AddInt64(&callCount, 1) == 1 {
+							return "V1", nil
+						}
+						once.Do(func() { close(started) })
+						<-release
+						return "V2", nil
+					}))
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(started, 2*refreshDuration).Should(BeClosed())
+				timeoutCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				Expect(cache.(Shutdowner).Shutdown(timeoutCtx)).To(Equal(context.DeadlineExceeded))
+			})
+
 			It("work with refreshLocal", func() {
 				if cache.CacheType() != TypeBoth {
 					return
@@ -579,7 +703,7 @@ var _ = Describe("Cache", func() {
 				Expect(value).To(Equal("V1"))
 
 				// shouldLoad SetNX true
-				jetCache.externalLoad(ctx, &refreshTask{key: key, do: doFunc, ttl: time.Minute}, time.Now())
+				jetCache.externalLoad(ctx, &refreshTask{key: key, do: doFunc, ttl: time.Minute, refreshDuration: refreshDuration}, time.Now())
 				err = cache.Get(ctx, key, &value)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(value).To(Equal("V2"))
@@ -587,7 +711,7 @@ var _ = Describe("Cache", func() {
 				// shouldLoad SetNX false, must refreshLocal
 				_, err = rdb.SetEx(ctx, key, "V3", time.Minute).Result()
 				Expect(err).NotTo(HaveOccurred())
-				jetCache.externalLoad(ctx, &refreshTask{key: key, do: doFunc, ttl: time.Minute}, time.Now())
+				jetCache.externalLoad(ctx, &refreshTask{key: key, do: doFunc, ttl: time.Minute, refreshDuration: refreshDuration}, time.Now())
 				b, ok := jetCache.local.Get(key)
 				Expect(ok).To(BeTrue())
 				Expect(string(b)).To(Equal("V3"))
@@ -614,7 +738,7 @@ var _ = Describe("Cache", func() {
 
 				perform(200, func(i int) {
 					rdb.Del(context.TODO(), lockKey)
-					jetCache.externalLoad(ctx, &refreshTask{key: key, do: doFunc, ttl: time.Minute}, time.Now())
+					jetCache.externalLoad(ctx, &refreshTask{key: key, do: doFunc, ttl: time.Minute, refreshDuration: refreshDuration}, time.Now())
 				})
 				b, ok := jetCache.local.Get(key)
 				Expect(ok).To(BeTrue())
@@ -784,6 +908,46 @@ var _ = Describe("Cache", func() {
 				}
 			})
 
+			It("Shutdown flushes pending events", func() {
+				if !cache.(*jetCache).isSyncLocal() {
+					return
+				}
+
+				var received int32
+				jetCache := New(WithName("flush"),
+					WithRemote(remote.NewGoRedisV9Adapter(rdb)),
+					WithLocal(localNew(freeCache)),
+					WithSyncLocal(true),
+					WithEventChBufSize(testEventChSize),
+					WithEventHandler(func(event *Event) {
+						atomic.AddInt32(&received, 1)
+					})).(*jetCache)
+
+				for i := 0; i < testEventChSize; i++ {
+					jetCache.send(EventTypeSet, key)
+				}
+				Expect(jetCache.Shutdown(context.Background())).To(Succeed())
+				Expect(atomic.LoadInt32(&received)).To(Equal(int32(testEventChSize)))
+			})
+
+			It("ClearLocalCache and DeleteFromLocalCacheByPrefix with sync local", func() {
+				var jetCache = cache.(*jetCache)
+				if !jetCache.isSyncLocal() {
+					return
+				}
+
+				Expect(jetCache.ClearLocalCache()).To(Succeed())
+				e, ok := <-jetCache.eventCh
+				Expect(ok).To(BeTrue())
+				Expect(e.EventType).To(Equal(EventTypeClearLocal))
+
+				Expect(jetCache.DeleteFromLocalCacheByPrefix("prefix:")).To(Succeed())
+				e, ok = <-jetCache.eventCh
+				Expect(ok).To(BeTrue())
+				Expect(e.EventType).To(Equal(EventTypeDeletePrefix))
+				Expect(e.Keys).To(Equal([]string{"prefix:"}))
+			})
+
 			It("send when eventCh full", func() {
 				var jetCache = cache.(*jetCache)
 				if !jetCache.isSyncLocal() {
@@ -871,6 +1035,19 @@ var _ = Describe("Cache", func() {
 			cache.Close()
 		})
 	})
+
+	Context("with local lacking optional capabilities", func() {
+		It("returns ErrLocalUnsupported", func() {
+			cache := New(WithLocal(mockLocal{}))
+			defer cache.Close()
+
+			Expect(cache.(LocalController).ClearLocalCache()).To(MatchError(ErrLocalUnsupported))
+			Expect(cache.(LocalController).DeleteFromLocalCacheByPrefix("any")).To(MatchError(ErrLocalUnsupported))
+			_, err := cache.(LocalController).LocalCacheLen()
+			Expect(err).To(MatchError(ErrLocalUnsupported))
+			Expect(cache.(LocalController).RangeLocalCache(func(string, []byte) bool { return true })).To(MatchError(ErrLocalUnsupported))
+		})
+	})
 })
 
 func newRdb() *redis.Client {
@@ -916,7 +1093,7 @@ func localNew(localType localType) local.Local {
 		return local.NewTinyLFU(100000, localExpire)
 	} else {
 		id := atomic.AddInt32(&localId, 1)
-		return local.NewFreeCache(256*local.MB, localExpire, strconv.Itoa(int(id)))
+		return local.NewFreeCache(10*local.MB, localExpire, strconv.Itoa(int(id)))
 	}
 }
 
@@ -996,6 +1173,18 @@ func (m mockGoRedisMGetMSetErrAdapter) Nil() error {
 	panic("implement me")
 }
 
+var _ local.Local = mockLocal{}
+
+type mockLocal struct{}
+
+func (mockLocal) Set(string, []byte) {}
+
+func (mockLocal) Get(string) ([]byte, bool) {
+	return nil, false
+}
+
+func (mockLocal) Del(string) {}
+
 type testLogger struct{}
 
 func (l *testLogger) Debug(format string, v ...any) {
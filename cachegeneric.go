@@ -1,13 +1,16 @@
 package cache
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"sort"
+	"sync"
+	"time"
 
 	"golang.org/x/exp/constraints"
+	"golang.org/x/sync/semaphore"
 
 	"github.com/mgtv-tech/jetcache-go/logger"
 	"github.com/mgtv-tech/jetcache-go/util"
@@ -16,11 +19,59 @@ import (
 // T wrap Cache to support golang's generics
 type T[K constraints.Ordered, V any] struct {
 	Cache
+	values          *valueTier[V] // decoded value tier, see WithValueCache.
+	valueOnly       bool
+	sizeOf          func(V) int64
+	batchSize       int // max ids per call of the MGet load function, see WithBatchSize.
+	loadConcurrency int
+	loadTimeout     time.Duration
+	coalesceWindow  time.Duration // see WithCoalesceWindow.
+	batchesMu       sync.Mutex
+	batches         map[batchKey]*pendingBatch[K, V] // pending coalesced loads by key and load function.
 }
 
-// NewT new a T
-func NewT[K constraints.Ordered, V any](cache Cache) *T[K, V] {
-	return &T[K, V]{cache}
+// batchKey identifies a pending coalesced load by the key and the code of the
+// load function. The closures of one function literal share their code, so
+// they are coalesced together whatever they capture.
+type batchKey struct {
+	key string
+	fn  uintptr
+}
+
+// pendingBatch is a coalesced load of the ids missed by concurrent MGet calls.
+type pendingBatch[K constraints.Ordered, V any] struct {
+	miss    map[string]K
+	flights flights
+	done    chan struct{} // closed once ret is set.
+	ret     BatchResult[K, V]
+}
+
+// NewT new a T. With WithValueCache, the value cache lives as long as the
+// cache, so create the T once rather than per call.
+func NewT[K constraints.Ordered, V any](cache Cache, opts ...TOption[V]) *T[K, V] {
+	var o tOptions[V]
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	w := &T[K, V]{
+		Cache:           cache,
+		batchSize:       o.batchSize,
+		loadConcurrency: o.loadConcurrency,
+		loadTimeout:     o.loadTimeout,
+		coalesceWindow:  o.coalesceWindow,
+	}
+	if w.loadConcurrency <= 0 {
+		w.loadConcurrency = defaultLoadConcurrency
+	}
+	if o.valueSize > 0 {
+		w.values = newValueTier(o)
+		w.valueOnly = o.valueOnly
+		w.sizeOf = o.sizeOf
+		cache.(*jetCache).addLocalTier(w.values)
+	}
+
+	return w
 }
 
 // Set sets the value `v` associated with the given `key` and `id` in the cache.
@@ -29,7 +80,40 @@ func (w *T[K, V]) Set(ctx context.Context, key string, id K, v V) error {
 	c := w.Cache.(*jetCache)
 
 	combKey := fmt.Sprintf("%s%s%v", key, c.separator, id)
-	return w.Cache.Set(ctx, combKey, Value(v))
+	return w.Cache.Set(ctx, combKey, Value(v), SkipLocal(w.valueOnly))
+}
+
+// Update sets the value associated with the given `key` and `id` to the value
+// returned by `fn` for the current value, or the zero value if it is missing,
+// and returns it. With a remote cache, the value is set by a compare-and-swap,
+// and `fn` is called again for the new current value when a concurrent update
+// won, up to the updateRetries. It returns ErrUpdateConflict when the retries
+// are exhausted. The Writer is not called.
+func (w *T[K, V]) Update(ctx context.Context, key string, id K, fn func(old V) (V, error)) (V, error) {
+	c := w.Cache.(*jetCache)
+
+	var v V
+	combKey := fmt.Sprintf("%s%s%v", key, c.separator, id)
+	_, err := c.update(ctx, combKey, w.valueOnly, func(b []byte) ([]byte, error) {
+		var old V
+		if b != nil {
+			if err := c.Unmarshal(b, &old); err != nil {
+				return nil, fmt.Errorf("Update#c.Unmarshal(%s) error(%v)", combKey, err)
+			}
+		}
+
+		var err error
+		if v, err = fn(old); err != nil {
+			return nil, err
+		}
+		return c.Marshal(v)
+	})
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+
+	return v, nil
 }
 
 // Get retrieves the value associated with the given `key` and `id`.
@@ -45,11 +129,29 @@ func (w *T[K, V]) Get(ctx context.Context, key string, id K, fn func(context.Con
 
 	var varT V
 	combKey := fmt.Sprintf("%s%s%v", key, c.separator, id)
-	err := w.Once(ctx, combKey, Value(&varT), Do(func(ctx context.Context) (any, error) {
+	if w.values == nil {
+		err := w.Once(ctx, combKey, Value(&varT), Do(func(ctx context.Context) (any, error) {
+			return fn(ctx, id)
+		}))
+		return varT, err
+	}
+
+	if v, ok := w.values.get(combKey); ok {
+		c.statsHandler.IncrHit()
+		c.statsHandler.IncrLocalHit()
+		return v, nil
+	}
+
+	seq := w.values.snapshot(combKey)
+	b, err := c.once(ctx, combKey, Value(&varT), SkipLocal(w.valueOnly), Do(func(ctx context.Context) (any, error) {
 		return fn(ctx, id)
 	}))
+	if err != nil {
+		return varT, err
+	}
+	w.setValue(combKey, varT, b, seq)
 
-	return varT, err
+	return w.cloneValue(varT), nil
 }
 
 // MGet efficiently retrieves multiple values associated with the given `key` and `ids`.
@@ -73,90 +175,252 @@ func (w *T[K, V]) MGet(ctx context.Context, key string, ids []K, fn func(context
 //
 // The results are returned as a map where the key is the `id` and the value is the corresponding data.
 // Any errors encountered during the cache retrieval or data fetching process are returned as a non-nil error.
+// See MGetResults for the status of each id.
 func (w *T[K, V]) MGetWithErr(ctx context.Context, key string, ids []K, fn func(context.Context, []K) (map[K]V, error)) (result map[K]V, errs error) {
+	ret := w.MGetResults(ctx, key, ids, fn)
+
+	return ret.Values(), ret.Err
+}
+
+// MGetResults is MGetWithErr returning the status of each id, with the error
+// of the ids failing to decode or to load, see BatchResult.
+func (w *T[K, V]) MGetResults(ctx context.Context, key string, ids []K, fn func(context.Context, []K) (map[K]V, error)) BatchResult[K, V] {
 	c := w.Cache.(*jetCache)
 
+	ret := newBatchResult[K, V](len(ids))
 	miss := make(map[string]K, len(ids))
 	for _, missId := range ids {
 		missKey := fmt.Sprintf("%s%s%v", key, c.separator, missId)
 		miss[missKey] = missId
 	}
 
-	if c.local != nil {
-		result, errs = w.mGetLocal(miss, true)
+	if c.local != nil || w.values != nil {
+		w.mGetLocal(miss, true, &ret)
 		if len(miss) == 0 {
-			return
+			return ret
 		}
 	}
 
 	if c.remote == nil && fn == nil {
-		return
+		ret.setMiss(miss)
+		return ret
 	}
 
-	missIds := make([]K, 0, len(miss))
-	for _, missId := range miss {
-		missIds = append(missIds, missId)
+	for attempt := 0; len(miss) > 0 && attempt < maxFlightAttempts; attempt++ {
+		w.mGetShared(ctx, key, miss, fn, &ret)
 	}
+	ret.setMiss(miss)
 
-	sort.Slice(missIds, func(i, j int) bool {
-		return missIds[i] < missIds[j]
-	})
+	return ret
+}
 
-	combKey := fmt.Sprintf("%s%s%v", key, c.separator, missIds)
-	v, err, _ := c.group.Do(combKey, func() (interface{}, error) {
-		var ret map[K]V
+// mGetShared reads the missed ids from the caches and loads them, sharing the
+// reads and loads in flight with the concurrent Once, Get and MGet calls of
+// the same keys. The ids of abandoned flights are left in miss to retry.
+func (w *T[K, V]) mGetShared(ctx context.Context, key string, miss map[string]K, fn func(context.Context, []K) (map[K]V, error), ret *BatchResult[K, V]) {
+	c := w.Cache.(*jetCache)
 
-		process := func(r map[K]V, e error) {
-			errs = errors.Join(errs, e)
-			ret = util.MergeMap(ret, r)
-		}
+	missKeys := make([]string, 0, len(miss))
+	for missKey := range miss {
+		missKeys = append(missKeys, missKey)
+	}
+	owned, waiting := c.inflight.claim(missKeys...)
+	defer owned.abandon()
 
-		if c.local != nil {
-			process(w.mGetLocal(miss, false))
-			if len(miss) == 0 {
-				return ret, nil
+	claimed := make(map[string]K, len(owned))
+	for missKey := range owned {
+		claimed[missKey] = miss[missKey]
+		delete(miss, missKey)
+	}
+
+	if len(claimed) > 0 && (c.local != nil || w.values != nil) {
+		w.mGetLocal(claimed, false, ret)
+	}
+	if len(claimed) > 0 && c.remote != nil {
+		w.mGetRemote(ctx, claimed, ret, owned)
+	}
+	var (
+		batch  *pendingBatch[K, V]
+		handed flights
+	)
+	if len(claimed) > 0 {
+		switch {
+		case fn == nil:
+			ret.setMiss(claimed)
+		case w.coalesceWindow > 0:
+			// the flights handed to the pending load are finished by it.
+			handed = make(flights, len(claimed))
+			for missKey, missId := range claimed {
+				handed[missKey] = owned[missKey]
+				delete(owned, missKey)
+				miss[missKey] = missId
 			}
+			owned.abandon()
+			batch = w.coalesce(ctx, key, claimed, handed, fn)
+		default:
+			w.mQueryAndSetCache(ctx, claimed, fn, ret, owned)
 		}
+	}
 
-		if c.remote != nil {
-			process(w.mGetRemote(ctx, miss))
-			if len(miss) == 0 {
-				return ret, nil
+	// the owned flights are finished before waiting, so that two calls never
+	// wait on each other.
+	owned.abandon()
+	w.mWait(waiting, miss, ret)
+	if batch != nil {
+		w.mWaitBatch(ctx, batch, handed, miss, ret)
+	}
+}
+
+// mWait waits for the flights of the missed ids, and sets their results.
+func (w *T[K, V]) mWait(waiting flights, miss map[string]K, ret *BatchResult[K, V]) {
+	c := w.Cache.(*jetCache)
+
+	for missKey, f := range waiting {
+		<-f.done
+		if f.err == errAbandoned {
+			continue
+		}
+
+		missId := miss[missKey]
+		delete(miss, missKey)
+		switch {
+		case c.isNotFoundPlaceholder(f.b), f.err != nil && c.errNotFound != nil && errors.Is(f.err, c.errNotFound):
+			ret.Items[missId] = ItemResult[V]{Status: StatusNotFound}
+		case f.err != nil:
+			ret.Items[missId] = ItemResult[V]{Status: StatusLoadError, Err: f.err}
+			ret.Err = errors.Join(ret.Err, fmt.Errorf("mWait(%s) error(%v)", missKey, f.err))
+		default:
+			var varT V
+			if err := c.Unmarshal(f.b, &varT); err != nil {
+				ret.fail(missId, StatusDecodeError, fmt.Errorf("mWait#c.Unmarshal(%s) error(%v)", missKey, err))
+			} else if f.cached {
+				ret.Items[missId] = ItemResult[V]{Value: varT, Status: StatusRemoteHit}
+			} else {
+				ret.Items[missId] = ItemResult[V]{Value: varT, Status: StatusLoaded}
 			}
 		}
+	}
+}
 
-		if fn != nil {
-			process(w.mQueryAndSetCache(ctx, miss, fn))
+// coalesce adds the missed ids to the pending load of the key and fn. If none
+// is pending, it starts one, which waits for the coalescing window and loads
+// all the ids added meanwhile by one fn call, see WithCoalesceWindow. The load
+// runs with the values of the ctx of the first caller but not its
+// cancellation, so that one canceled caller does not fail the others.
+func (w *T[K, V]) coalesce(ctx context.Context, key string, miss map[string]K, fl flights, fn func(context.Context, []K) (map[K]V, error)) *pendingBatch[K, V] {
+	bk := batchKey{key: key, fn: reflect.ValueOf(fn).Pointer()}
+	w.batchesMu.Lock()
+	batch, pending := w.batches[bk]
+	if !pending {
+		if w.batches == nil {
+			w.batches = make(map[batchKey]*pendingBatch[K, V])
 		}
+		batch = &pendingBatch[K, V]{miss: make(map[string]K), flights: make(flights), done: make(chan struct{})}
+		w.batches[bk] = batch
+	}
+	for missKey, missId := range miss {
+		batch.miss[missKey] = missId
+		batch.flights[missKey] = fl[missKey]
+	}
+	w.batchesMu.Unlock()
 
-		return ret, nil
-	})
+	if !pending {
+		go w.loadBatch(context.WithoutCancel(ctx), bk, batch, fn)
+	}
 
-	if err != nil {
-		errs = errors.Join(errs, err)
+	return batch
+}
+
+// loadBatch loads the ids of the pending batch once the coalescing window is
+// over, and finishes their flights.
+func (w *T[K, V]) loadBatch(ctx context.Context, bk batchKey, batch *pendingBatch[K, V], fn func(context.Context, []K) (map[K]V, error)) {
+	defer close(batch.done)
+	defer batch.flights.abandon()
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("panic(%v)", r)
+			batch.ret = newBatchResult[K, V](len(batch.miss))
+			batch.ret.failLoad(util.MapValues(batch.miss), err)
+			for missKey := range batch.miss {
+				batch.flights.finish(missKey, nil, false, err)
+			}
+		}
+	}()
+
+	time.Sleep(w.coalesceWindow)
+
+	w.batchesMu.Lock()
+	delete(w.batches, bk)
+	w.batchesMu.Unlock()
+
+	batch.ret = newBatchResult[K, V](len(batch.miss))
+	w.mQueryAndSetCache(ctx, batch.miss, fn, &batch.ret, batch.flights)
+}
+
+// mWaitBatch waits for the pending batch finishing the flights of the missed
+// ids, unless ctx is done first, and sets their results along with the errors
+// of the batch.
+func (w *T[K, V]) mWaitBatch(ctx context.Context, batch *pendingBatch[K, V], fl flights, miss map[string]K, ret *BatchResult[K, V]) {
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		ids := make([]K, 0, len(fl))
+		for missKey := range fl {
+			ids = append(ids, miss[missKey])
+			delete(miss, missKey)
+		}
+		ret.failLoad(ids, ctx.Err())
 		return
 	}
 
-	return util.MergeMap(result, v.(map[K]V)), errs
+	// the values are decoded from the flights, so that each caller gets its own copy.
+	batchRet := newBatchResult[K, V](len(fl))
+	w.mWait(fl, miss, &batchRet)
+	for id, item := range batchRet.Items {
+		ret.Items[id] = item
+	}
+	ret.Err = errors.Join(ret.Err, batch.ret.Err)
 }
 
-func (w *T[K, V]) mGetLocal(miss map[string]K, skipMissStats bool) (result map[K]V, errs error) {
+func (w *T[K, V]) mGetLocal(miss map[string]K, skipMissStats bool, ret *BatchResult[K, V]) {
 	c := w.Cache.(*jetCache)
 
-	result = make(map[K]V, len(miss))
 	for missKey, missId := range miss {
-		if b, ok := c.local.Get(missKey); ok {
+		if w.values != nil {
+			if v, ok := w.values.get(missKey); ok {
+				delete(miss, missKey)
+				c.statsHandler.IncrHit()
+				c.statsHandler.IncrLocalHit()
+				ret.Items[missId] = ItemResult[V]{Value: v, Status: StatusLocalHit}
+				continue
+			}
+		}
+
+		var seq uint64
+		if w.values != nil {
+			seq = w.values.snapshot(missKey)
+		}
+		if c.local == nil || w.valueOnly {
+			if !skipMissStats {
+				c.statsHandler.IncrLocalMiss()
+				if c.remote == nil {
+					c.statsHandler.IncrMiss()
+				}
+			}
+		} else if b, ok := c.local.Get(missKey); ok {
 			delete(miss, missKey)
 			c.statsHandler.IncrHit()
 			c.statsHandler.IncrLocalHit()
-			if bytes.Compare(b, notFoundPlaceholder) == 0 {
+			if c.isNotFoundPlaceholder(b) {
+				ret.Items[missId] = ItemResult[V]{Status: StatusNotFound}
 				continue
 			}
 			var varT V
 			if err := c.Unmarshal(b, &varT); err != nil {
-				errs = errors.Join(errs, fmt.Errorf("mGetLocal#c.Unmarshal(%s) error(%v)", missKey, err))
+				ret.fail(missId, StatusDecodeError, fmt.Errorf("mGetLocal#c.Unmarshal(%s) error(%v)", missKey, err))
 			} else {
-				result[missId] = varT
+				w.setValue(missKey, varT, b, seq)
+				ret.Items[missId] = ItemResult[V]{Value: w.cloneValue(varT), Status: StatusLocalHit}
 			}
 		} else if !skipMissStats {
 			c.statsHandler.IncrLocalMiss()
@@ -165,40 +429,50 @@ func (w *T[K, V]) mGetLocal(miss map[string]K, skipMissStats bool) (result map[K
 			}
 		}
 	}
-
-	return
 }
 
-func (w *T[K, V]) mGetRemote(ctx context.Context, miss map[string]K) (result map[K]V, errs error) {
+func (w *T[K, V]) mGetRemote(ctx context.Context, miss map[string]K, ret *BatchResult[K, V], fl flights) {
 	c := w.Cache.(*jetCache)
 
 	missKeys := make([]string, 0, len(miss))
+	seqs := make(map[string]uint64, len(miss))
 	for missKey := range miss {
 		missKeys = append(missKeys, missKey)
+		if w.values != nil {
+			seqs[missKey] = w.values.snapshot(missKey)
+		}
 	}
 
 	cacheValues, err := c.remote.MGet(ctx, missKeys...)
 	if err != nil {
-		errs = errors.Join(errs, fmt.Errorf("mGetRemote#c.Remote.MGet error(%v)", err))
+		ret.Err = errors.Join(ret.Err, fmt.Errorf("mGetRemote#c.Remote.MGet error(%v)", err))
 		return
 	}
 
-	result = make(map[K]V, len(cacheValues))
 	for missKey, missId := range miss {
 		if val, ok := cacheValues[missKey]; ok {
+			b, ok := c.verify(ctx, missKey, util.Bytes(val.(string)))
+			if !ok {
+				c.statsHandler.IncrMiss()
+				c.statsHandler.IncrRemoteMiss()
+				continue
+			}
 			delete(miss, missKey)
 			c.statsHandler.IncrHit()
 			c.statsHandler.IncrRemoteHit()
-			b := util.Bytes(val.(string))
-			if bytes.Compare(b, notFoundPlaceholder) == 0 {
+			if c.isNotFoundPlaceholder(b) {
+				fl.finish(missKey, b, true, nil)
+				ret.Items[missId] = ItemResult[V]{Status: StatusNotFound}
 				continue
 			}
 			var varT V
 			if err = c.Unmarshal(b, &varT); err != nil {
-				errs = errors.Join(errs, fmt.Errorf("mGetRemote#c.Unmarshal(%s) error(%v)", missKey, err))
+				ret.fail(missId, StatusDecodeError, fmt.Errorf("mGetRemote#c.Unmarshal(%s) error(%v)", missKey, err))
 			} else {
-				result[missId] = varT
-				if c.local != nil {
+				fl.finish(missKey, b, true, nil)
+				w.setValue(missKey, varT, b, seqs[missKey])
+				ret.Items[missId] = ItemResult[V]{Value: w.cloneValue(varT), Status: StatusRemoteHit}
+				if c.local != nil && !w.valueOnly {
 					c.local.Set(missKey, b)
 				}
 			}
@@ -207,44 +481,134 @@ func (w *T[K, V]) mGetRemote(ctx context.Context, miss map[string]K) (result map
 			c.statsHandler.IncrRemoteMiss()
 		}
 	}
+}
 
-	return
+// mQueryAndSetCache loads the missed ids by fn and caches them. With
+// WithBatchSize, the ids are split into chunks loaded concurrently, and the
+// failure of a chunk only fails its own ids. The flights of the ids are
+// finished with their results.
+func (w *T[K, V]) mQueryAndSetCache(ctx context.Context, miss map[string]K, fn func(context.Context, []K) (map[K]V, error), ret *BatchResult[K, V], fl flights) {
+	loadCtx := ctx
+	if w.loadTimeout > 0 {
+		var cancel context.CancelFunc
+		loadCtx, cancel = context.WithTimeout(ctx, w.loadTimeout)
+		defer cancel()
+	}
+
+	if w.batchSize <= 0 || len(miss) <= w.batchSize {
+		w.mQueryChunk(ctx, loadCtx, miss, fn, ret, fl)
+		return
+	}
+
+	chunks := w.splitMiss(miss)
+	rets := make([]BatchResult[K, V], len(chunks))
+	sem := semaphore.NewWeighted(int64(w.loadConcurrency))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		i, chunk := i, chunk
+		rets[i] = newBatchResult[K, V](len(chunk))
+		// Acquire only fails once the load deadline is exceeded.
+		if err := sem.Acquire(loadCtx, 1); err != nil {
+			rets[i].failLoad(util.MapValues(chunk), err)
+			for missKey := range chunk {
+				fl.finish(missKey, nil, false, err)
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer sem.Release(1)
+			defer func() {
+				if r := recover(); r != nil {
+					err := fmt.Errorf("panic(%v)", r)
+					rets[i] = newBatchResult[K, V](len(chunk))
+					rets[i].failLoad(util.MapValues(chunk), err)
+					for missKey := range chunk {
+						fl.finish(missKey, nil, false, err)
+					}
+				}
+			}()
+
+			w.mQueryChunk(ctx, loadCtx, chunk, fn, &rets[i], fl)
+		}()
+	}
+	wg.Wait()
+
+	for _, chunkRet := range rets {
+		for id, item := range chunkRet.Items {
+			ret.Items[id] = item
+		}
+		ret.Err = errors.Join(ret.Err, chunkRet.Err)
+	}
 }
 
-func (w *T[K, V]) mQueryAndSetCache(ctx context.Context, miss map[string]K, fn func(context.Context, []K) (map[K]V, error)) (result map[K]V, errs error) {
-	c := w.Cache.(*jetCache)
+// splitMiss splits the missed ids, sorted, into chunks of at most batchSize ids.
+func (w *T[K, V]) splitMiss(miss map[string]K) []map[string]K {
+	missKeys := make([]string, 0, len(miss))
+	for missKey := range miss {
+		missKeys = append(missKeys, missKey)
+	}
+	sort.Slice(missKeys, func(i, j int) bool {
+		return miss[missKeys[i]] < miss[missKeys[j]]
+	})
 
-	missIds := make([]K, 0, len(miss))
-	for _, missId := range miss {
-		missIds = append(missIds, missId)
+	chunks := make([]map[string]K, 0, (len(missKeys)+w.batchSize-1)/w.batchSize)
+	for start := 0; start < len(missKeys); start += w.batchSize {
+		end := min(start+w.batchSize, len(missKeys))
+		chunk := make(map[string]K, end-start)
+		for _, missKey := range missKeys[start:end] {
+			chunk[missKey] = miss[missKey]
+		}
+		chunks = append(chunks, chunk)
 	}
 
+	return chunks
+}
+
+// mQueryChunk loads the missed ids by fn under loadCtx, and caches them under ctx.
+func (w *T[K, V]) mQueryChunk(ctx, loadCtx context.Context, miss map[string]K, fn func(context.Context, []K) (map[K]V, error), ret *BatchResult[K, V], fl flights) {
+	c := w.Cache.(*jetCache)
+
+	missIds := util.MapValues(miss)
 	c.statsHandler.IncrQuery()
-	fnValues, err := fn(ctx, missIds)
+	fnValues, err := fn(loadCtx, missIds)
 	if err != nil {
-		errs = errors.Join(errs, fmt.Errorf("mQueryAndSetCache#fn(%v) error(%v)", missIds, err))
 		c.statsHandler.IncrQueryFail(err)
+		ret.failLoad(missIds, err)
+		for missKey := range miss {
+			fl.finish(missKey, nil, false, err)
+		}
 		return
 	}
 
-	result = make(map[K]V, len(fnValues))
 	cacheValues := make(map[string]any, len(miss))
 	placeholderValues := make(map[string]any, len(miss))
 	for missKey, missId := range miss {
 		if val, ok := fnValues[missId]; ok {
-			result[missId] = val
+			item := ItemResult[V]{Value: val, Status: StatusLoaded}
 			if b, err := c.Marshal(val); err != nil {
-				placeholderValues[missKey] = notFoundPlaceholder
-				errs = errors.Join(errs, fmt.Errorf("mQueryAndSetCache#c.Marshal error(%v)", err))
+				item.Status = StatusEncodeError
+				item.Err = fmt.Errorf("mQueryAndSetCache#c.Marshal error(%v)", err)
+				ret.Err = errors.Join(ret.Err, item.Err)
+				fl.finish(missKey, nil, false, item.Err)
 			} else {
 				cacheValues[missKey] = b
+				fl.finish(missKey, b, false, nil)
 			}
+			ret.Items[missId] = item
 		} else {
-			placeholderValues[missKey] = notFoundPlaceholder
+			placeholderValues[missKey] = c.placeholder()
+			ret.Items[missId] = ItemResult[V]{Status: StatusNotFound}
+			fl.finish(missKey, c.placeholder(), false, nil)
 		}
 	}
 
-	if c.local != nil {
+	for key := range cacheValues {
+		c.delLocalTiers(key)
+	}
+	if c.local != nil && !w.valueOnly {
 		if len(cacheValues) > 0 {
 			for key, value := range cacheValues {
 				c.local.Set(key, value.([]byte))
@@ -258,14 +622,22 @@ func (w *T[K, V]) mQueryAndSetCache(ctx context.Context, miss map[string]K, fn f
 	}
 
 	if c.remote != nil {
+		if c.checksum != nil {
+			for key, value := range cacheValues {
+				cacheValues[key] = c.seal(key, value.([]byte))
+			}
+			for key, value := range placeholderValues {
+				placeholderValues[key] = c.seal(key, value.([]byte))
+			}
+		}
 		if len(cacheValues) > 0 {
 			if err = c.remote.MSet(ctx, cacheValues, c.remoteExpiry); err != nil {
-				errs = errors.Join(errs, fmt.Errorf("mQueryAndSetCache#c.Remote.MSet error(%v)", err))
+				ret.Err = errors.Join(ret.Err, fmt.Errorf("mQueryAndSetCache#c.Remote.MSet error(%v)", err))
 			}
 		}
 		if len(placeholderValues) > 0 {
 			if err = c.remote.MSet(ctx, placeholderValues, c.notFoundExpiry); err != nil {
-				errs = errors.Join(errs, fmt.Errorf("mQueryAndSetCache#c.Remote.MSet error(%v)", err))
+				ret.Err = errors.Join(ret.Err, fmt.Errorf("mQueryAndSetCache#c.Remote.MSet error(%v)", err))
 			}
 		}
 		if c.isSyncLocal() {
@@ -276,6 +648,26 @@ func (w *T[K, V]) mQueryAndSetCache(ctx context.Context, miss map[string]K, fn f
 			c.send(EventTypeSetByMGet, cacheKeys...)
 		}
 	}
+}
 
-	return
+// setValue stores the value decoded from b in the value cache, see valueTier.set.
+func (w *T[K, V]) setValue(key string, v V, b []byte, seq uint64) {
+	if w.values == nil {
+		return
+	}
+
+	size := int64(len(b))
+	if w.sizeOf != nil {
+		size = w.sizeOf(v)
+	}
+	w.values.set(key, v, size, seq)
+}
+
+// cloneValue copies the value stored in the value cache before returning it.
+func (w *T[K, V]) cloneValue(v V) V {
+	if w.values == nil || w.values.clone == nil {
+		return v
+	}
+
+	return w.values.clone(v)
 }
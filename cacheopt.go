@@ -5,8 +5,10 @@ import (
 	"time"
 
 	"github.com/mgtv-tech/jetcache-go/encoding"
+	_ "github.com/mgtv-tech/jetcache-go/encoding/gob"
 	_ "github.com/mgtv-tech/jetcache-go/encoding/json"
 	"github.com/mgtv-tech/jetcache-go/encoding/msgpack"
+	_ "github.com/mgtv-tech/jetcache-go/encoding/proto"
 	_ "github.com/mgtv-tech/jetcache-go/encoding/sonic"
 	"github.com/mgtv-tech/jetcache-go/local"
 	"github.com/mgtv-tech/jetcache-go/remote"
@@ -33,6 +35,8 @@ const (
 	EventTypeSetByRefresh EventType = 3
 	EventTypeSetByMGet    EventType = 4
 	EventTypeDelete       EventType = 5
+	EventTypeClearLocal   EventType = 6 // Clears the local cache, Keys is empty.
+	EventTypeDeletePrefix EventType = 7 // Deletes the local keys with the prefixes in Keys.
 )
 
 type (
@@ -41,6 +45,7 @@ type (
 		name                       string             // Cache name, used for log identification and metric reporting
 		remote                     remote.Remote      // Remote is distributed cache, such as Redis.
 		local                      local.Local        // Local is memory cache, such as FreeCache.
+		sharedLocal                bool               // The local is shared by the caches of a Manager, see WithSharedLocal.
 		codec                      string             // Value encoding and decoding method. Default is "msgpack.Name". You can also customize it.
 		errNotFound                error              // Error to return for cache miss. Used to prevent cache penetration.
 		remoteExpiry               time.Duration      // Remote cache ttl, Default is 1 hour.
@@ -57,11 +62,43 @@ type (
 		eventHandler               func(event *Event) // Function to handle local cache invalidation events.
 		separatorDisabled          bool               // Disable separator for cache key. Default is false. If true, the cache key will not be split into multiple parts.
 		separator                  string             // Separator for cache key. Default is ":".
+		checksum                   Checksum           // Integrity trailer of the remote values. Default is nil (disabled).
+		evictCorrupted             bool               // Delete the remote values failing the integrity check.
+		placeholderMode            PlaceholderMode    // Not found placeholder written and read. Default is PlaceholderLegacy.
+		writer                     Writer             // Writes the values set and the keys deleted to the data source. Default is nil (cache-only).
+		writeMode                  WriteMode          // Mode of the writer. Default is WriteThrough.
+		writeQueueSize             int                // Maximum number of pending writes in WriteBehind mode. Default is 1024.
+		writeRetries               int                // Retries of a failed write in WriteBehind mode. Default is 3.
+		writeRetryBackoff          time.Duration      // Backoff before the first retry, doubled by each retry. Default is 100ms.
+		writeFlushTimeout          time.Duration      // Maximum time Close waits for the pending writes in WriteBehind mode. Default is 10s.
+		deleteRetries              int                // Retries of a failed delete of DeleteWithDelay. Default is 3.
+		deleteRetryBackoff         time.Duration      // Backoff before the first retry, doubled by each retry. Default is 100ms.
+		updateRetries              int                // Retries of an Update losing to a concurrent update. Default is 10.
+		updateRetryBackoff         time.Duration      // Backoff before the first retry, doubled by each retry and jittered. Default is 5ms.
 	}
 
 	// Option defines the method to customize an Options.
 	Option func(o *Options)
 
+	// Config is a read-only view of the cache Options, used for inspection.
+	Config struct {
+		Name                       string        `json:"name"`
+		CacheType                  string        `json:"cacheType"`
+		Codec                      string        `json:"codec"`
+		RemoteExpiry               time.Duration `json:"remoteExpiry"`
+		NotFoundExpiry             time.Duration `json:"notFoundExpiry"`
+		Offset                     time.Duration `json:"offset"`
+		RefreshDuration            time.Duration `json:"refreshDuration"`
+		StopRefreshAfterLastAccess time.Duration `json:"stopRefreshAfterLastAccess"`
+		RefreshConcurrency         int           `json:"refreshConcurrency"`
+		StatsDisabled              bool          `json:"statsDisabled"`
+		SourceID                   string        `json:"sourceID"`
+		SyncLocal                  bool          `json:"syncLocal"`
+		EventChBufSize             int           `json:"eventChBufSize"`
+		Separator                  string        `json:"separator"`
+		ErrNotFound                error         `json:"-"`
+	}
+
 	EventType int
 
 	Event struct {
@@ -116,6 +153,36 @@ func newOptions(opts ...Option) Options {
 	if o.separator == "" && !o.separatorDisabled {
 		o.separator = defaultSeparator
 	}
+	if o.writeQueueSize <= 0 {
+		o.writeQueueSize = defaultWriteQueueSize
+	}
+	if o.writeRetries < 0 {
+		o.writeRetries = 0
+	} else if o.writeRetries == 0 {
+		o.writeRetries = defaultWriteRetries
+	}
+	if o.writeRetryBackoff <= 0 {
+		o.writeRetryBackoff = defaultWriteRetryBackoff
+	}
+	if o.writeFlushTimeout <= 0 {
+		o.writeFlushTimeout = defaultWriteFlushTimeout
+	}
+	if o.deleteRetries < 0 {
+		o.deleteRetries = 0
+	} else if o.deleteRetries == 0 {
+		o.deleteRetries = defaultDeleteRetries
+	}
+	if o.deleteRetryBackoff <= 0 {
+		o.deleteRetryBackoff = defaultDeleteRetryBackoff
+	}
+	if o.updateRetries < 0 {
+		o.updateRetries = 0
+	} else if o.updateRetries == 0 {
+		o.updateRetries = defaultUpdateRetries
+	}
+	if o.updateRetryBackoff <= 0 {
+		o.updateRetryBackoff = defaultUpdateRetryBackoff
+	}
 	if encoding.GetCodec(o.codec) == nil {
 		panic(fmt.Sprintf("encoding %s is not registered, please register it first", o.codec))
 	}
@@ -237,3 +304,101 @@ func WithSeparator(separator string) Option {
 		}
 	}
 }
+
+func WithChecksum(checksum Checksum) Option {
+	return func(o *Options) {
+		o.checksum = checksum
+	}
+}
+
+func WithEvictCorrupted(evictCorrupted bool) Option {
+	return func(o *Options) {
+		o.evictCorrupted = evictCorrupted
+	}
+}
+
+// WithPlaceholderMode sets the not found placeholder written and read,
+// defaulting to PlaceholderLegacy. Roll out the new placeholder in two phases:
+// switch every instance to PlaceholderUpgrade, then to PlaceholderNew once the
+// legacy placeholders have expired. Until an instance is switched to
+// PlaceholderNew, it still reads a cached "*" string value as not found.
+func WithPlaceholderMode(placeholderMode PlaceholderMode) Option {
+	return func(o *Options) {
+		o.placeholderMode = placeholderMode
+	}
+}
+
+// WithWriter sets the Writer of the values set by Set and the keys deleted by
+// Delete to the data source, see WithWriteMode. The values loaded by Once, MGet
+// or the refresh are not written.
+func WithWriter(writer Writer) Option {
+	return func(o *Options) {
+		o.writer = writer
+	}
+}
+
+func WithWriteMode(writeMode WriteMode) Option {
+	return func(o *Options) {
+		o.writeMode = writeMode
+	}
+}
+
+func WithWriteQueueSize(writeQueueSize int) Option {
+	return func(o *Options) {
+		o.writeQueueSize = writeQueueSize
+	}
+}
+
+// WithWriteRetries sets the retries of a failed write in WriteBehind mode,
+// defaulting to 3. A negative value disables the retries.
+func WithWriteRetries(writeRetries int) Option {
+	return func(o *Options) {
+		o.writeRetries = writeRetries
+	}
+}
+
+func WithWriteRetryBackoff(writeRetryBackoff time.Duration) Option {
+	return func(o *Options) {
+		o.writeRetryBackoff = writeRetryBackoff
+	}
+}
+
+// WithWriteFlushTimeout sets the maximum time Close waits for the pending
+// writes in WriteBehind mode, defaulting to 10s. The writes still pending are
+// then dropped, and the context passed to the Writer is canceled. Shutdown is
+// bounded by its own ctx instead.
+func WithWriteFlushTimeout(writeFlushTimeout time.Duration) Option {
+	return func(o *Options) {
+		o.writeFlushTimeout = writeFlushTimeout
+	}
+}
+
+// WithDeleteRetries sets the retries of a failed delete of DeleteWithDelay,
+// defaulting to 3. A negative value disables the retries.
+func WithDeleteRetries(deleteRetries int) Option {
+	return func(o *Options) {
+		o.deleteRetries = deleteRetries
+	}
+}
+
+func WithDeleteRetryBackoff(deleteRetryBackoff time.Duration) Option {
+	return func(o *Options) {
+		o.deleteRetryBackoff = deleteRetryBackoff
+	}
+}
+
+// WithUpdateRetries sets the retries of a T.Update losing to a concurrent
+// update, defaulting to 10. A negative value disables the retries.
+func WithUpdateRetries(updateRetries int) Option {
+	return func(o *Options) {
+		o.updateRetries = updateRetries
+	}
+}
+
+// WithUpdateRetryBackoff sets the backoff before the first retry of a T.Update,
+// defaulting to 5ms. It doubles by each retry, up to 32 times, and is jittered.
+func WithUpdateRetryBackoff(updateRetryBackoff time.Duration) Option {
+	return func(o *Options) {
+		o.updateRetryBackoff = updateRetryBackoff
+	}
+}
@@ -1,12 +1,16 @@
 package cache
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 
+	"github.com/mgtv-tech/jetcache-go/encoding/gob"
 	"github.com/mgtv-tech/jetcache-go/encoding/json"
+	"github.com/mgtv-tech/jetcache-go/encoding/proto"
 	"github.com/mgtv-tech/jetcache-go/stats"
 )
 
@@ -129,6 +133,8 @@ func TestCacheOptions(t *testing.T) {
 		assert.NotPanics(t, func() { newOptions(WithCodec("sonic")) })
 		assert.NotPanics(t, func() { newOptions(WithCodec("json")) })
 		assert.NotPanics(t, func() { newOptions(WithCodec("msgpack")) })
+		assert.NotPanics(t, func() { newOptions(WithCodec("proto")) })
+		assert.NotPanics(t, func() { newOptions(WithCodec("gob")) })
 	})
 
 	t.Run("with not registered codec", func(t *testing.T) {
@@ -160,3 +166,61 @@ func TestCacheOptionsRefreshDuration(t *testing.T) {
 		assert.Equal(t, v.expect, o.refreshDuration)
 	}
 }
+
+func TestCacheCodecFastPath(t *testing.T) {
+	for _, name := range []string{proto.Name, gob.Name} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			c := New(WithName(name), WithLocal(localNew(freeCache)), WithCodec(name)).(*jetCache)
+			defer c.Close()
+
+			b, err := c.Marshal([]byte("bytes"))
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("bytes"), b)
+			b, err = c.Marshal("string")
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("string"), b)
+			b, err = c.Marshal(nil)
+			assert.NoError(t, err)
+			assert.Nil(t, b)
+
+			var bs []byte
+			assert.NoError(t, c.Unmarshal([]byte("bytes"), &bs))
+			assert.Equal(t, []byte("bytes"), bs)
+			var str string
+			assert.NoError(t, c.Unmarshal([]byte("string"), &str))
+			assert.Equal(t, "string", str)
+			assert.NoError(t, c.Unmarshal(nil, &str))
+			assert.NoError(t, c.Unmarshal([]byte("string"), nil))
+
+			assert.NoError(t, c.Set(context.Background(), "key", Value("value")))
+			assert.NoError(t, c.Get(context.Background(), "key", &str))
+			assert.Equal(t, "value", str)
+		})
+	}
+
+	t.Run("proto message", func(t *testing.T) {
+		c := New(WithName("proto"), WithLocal(localNew(freeCache)), WithCodec(proto.Name))
+		defer c.Close()
+
+		cacheT := NewT[int, *wrapperspb.StringValue](c)
+		assert.NoError(t, cacheT.Set(context.Background(), "proto", 1, wrapperspb.String("value")))
+		v, err := cacheT.Get(context.Background(), "proto", 1, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "value", v.GetValue())
+
+		err = c.Set(context.Background(), "proto:2", Value(&object{Str: "str"}))
+		assert.ErrorIs(t, err, proto.ErrNotMessage)
+	})
+
+	t.Run("gob struct", func(t *testing.T) {
+		c := New(WithName("gob"), WithLocal(localNew(freeCache)), WithCodec(gob.Name))
+		defer c.Close()
+
+		cacheT := NewT[int, *object](c)
+		assert.NoError(t, cacheT.Set(context.Background(), "gob", 1, &object{Str: "str", Num: 1}))
+		v, err := cacheT.Get(context.Background(), "gob", 1, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, &object{Str: "str", Num: 1}, v)
+	})
+}
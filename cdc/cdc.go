@@ -0,0 +1,259 @@
+// Package cdc invalidates cache keys from a change data capture stream, such
+// as a MySQL binlog or an outbox table, rather than from application code.
+//
+// An Invalidator reads the row changes of a Stream, maps them to cache keys
+// by Rules, deletes the keys in batches with Cache.Invalidate, which sends the
+// sync events, and acknowledges the changes once deleted. Changes are thus
+// applied at least once: the unacknowledged ones are delivered again.
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go"
+	"github.com/mgtv-tech/jetcache-go/logger"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = 100 * time.Millisecond
+	defaultRetryBackoff  = 100 * time.Millisecond
+	maxRetryBackoff      = 10 * time.Second
+)
+
+const (
+	Insert Op = "insert"
+	Update Op = "update"
+	Delete Op = "delete"
+)
+
+type (
+	// Op is the operation of a Change.
+	Op string
+
+	// Change is a row change of the data source.
+	Change struct {
+		Table    string         `json:"table"`
+		Op       Op             `json:"op"`
+		Before   map[string]any `json:"before,omitempty"` // The row before the change, nil for inserts.
+		After    map[string]any `json:"after,omitempty"`  // The row after the change, nil for deletes.
+		Position string         `json:"position,omitempty"`
+	}
+
+	// Stream is a change stream. Changes not acknowledged by Ack are delivered
+	// again, such as after a restart.
+	Stream interface {
+		// Next returns the next change, blocking until one is available or ctx
+		// is done. It returns io.EOF once the stream ends.
+		Next(ctx context.Context) (Change, error)
+		// Ack acknowledges the changes, in the order returned by Next.
+		Ack(ctx context.Context, changes ...Change) error
+	}
+
+	// Rule maps the changes of a table to the cache keys to invalidate: the
+	// Keys, and the IDs of the T key Key, formatted as T does.
+	Rule struct {
+		Table string // The table of the changes, or "" for all the tables.
+		Keys  func(ch Change) []string
+		Key   string
+		IDs   func(ch Change) []any
+	}
+
+	// Option defines the method to customize an Invalidator.
+	Option func(i *Invalidator)
+
+	// Invalidator deletes the cache keys mapped from the changes of a Stream.
+	Invalidator struct {
+		cache         cache.Invalidator
+		stream        Stream
+		rules         []Rule
+		separator     string
+		batchSize     int
+		flushInterval time.Duration
+		retryBackoff  time.Duration
+	}
+)
+
+// WithBatchSize sets the maximum number of changes applied at once,
+// defaulting to 100.
+func WithBatchSize(batchSize int) Option {
+	return func(i *Invalidator) {
+		i.batchSize = batchSize
+	}
+}
+
+// WithFlushInterval sets how long changes are collected before being
+// applied, defaulting to 100ms.
+func WithFlushInterval(flushInterval time.Duration) Option {
+	return func(i *Invalidator) {
+		i.flushInterval = flushInterval
+	}
+}
+
+// WithRetryBackoff sets the backoff before retrying a failed batch, doubled
+// by each retry up to 10s, defaulting to 100ms.
+func WithRetryBackoff(retryBackoff time.Duration) Option {
+	return func(i *Invalidator) {
+		i.retryBackoff = retryBackoff
+	}
+}
+
+// Column returns the IDs of a Rule: the values of the column in the rows
+// before and after the change, without duplicates.
+func Column(column string) func(ch Change) []any {
+	return func(ch Change) []any {
+		var ids []any
+		for _, row := range []map[string]any{ch.Before, ch.After} {
+			v, ok := row[column]
+			if !ok || v == nil {
+				continue
+			}
+			if len(ids) == 1 && fmt.Sprint(ids[0]) == fmt.Sprint(v) {
+				continue
+			}
+			ids = append(ids, v)
+		}
+		return ids
+	}
+}
+
+// New returns an Invalidator of the cache keys mapped from the changes of the
+// stream by the rules. The cache must implement cache.Invalidator and
+// cache.Inspector, as the caches created by cache.New do.
+func New(c cache.Cache, stream Stream, rules []Rule, opts ...Option) *Invalidator {
+	invalidator, ok := c.(cache.Invalidator)
+	inspector, ok2 := c.(cache.Inspector)
+	if !ok || !ok2 {
+		panic("cdc: cache does not implement cache.Invalidator and cache.Inspector")
+	}
+
+	i := &Invalidator{
+		cache:         invalidator,
+		stream:        stream,
+		rules:         rules,
+		separator:     inspector.Config().Separator,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		retryBackoff:  defaultRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	if i.batchSize <= 0 {
+		i.batchSize = defaultBatchSize
+	}
+
+	return i
+}
+
+// Run applies the changes of the stream until it ends, returning nil, or ctx
+// is done, returning ctx.Err(). Failed batches are retried until applied.
+func (i *Invalidator) Run(ctx context.Context) error {
+	for {
+		batch, err := i.next(ctx)
+		if len(batch) > 0 {
+			if e := i.apply(ctx, batch); e != nil {
+				return e
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// Keys returns the cache keys mapped from the change by the rules.
+func (i *Invalidator) Keys(ch Change) []string {
+	var keys []string
+	for _, rule := range i.rules {
+		if rule.Table != "" && rule.Table != ch.Table {
+			continue
+		}
+		if rule.Keys != nil {
+			keys = append(keys, rule.Keys(ch)...)
+		}
+		if rule.IDs != nil {
+			for _, id := range rule.IDs(ch) {
+				keys = append(keys, fmt.Sprintf("%s%s%v", rule.Key, i.separator, id))
+			}
+		}
+	}
+
+	return keys
+}
+
+// next returns the changes read until the batch is full or the flush interval
+// elapsed since the first one, with the error that stopped the reading if any.
+func (i *Invalidator) next(ctx context.Context) ([]Change, error) {
+	ch, err := i.stream.Next(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := []Change{ch}
+	flushCtx, cancel := context.WithTimeout(ctx, i.flushInterval)
+	defer cancel()
+	for len(batch) < i.batchSize {
+		ch, err = i.stream.Next(flushCtx)
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				err = nil
+			}
+			return batch, err
+		}
+		batch = append(batch, ch)
+	}
+
+	return batch, nil
+}
+
+// apply invalidates the keys of the changes and acknowledges them, retrying
+// until done or ctx is done.
+func (i *Invalidator) apply(ctx context.Context, batch []Change) error {
+	seen := make(map[string]struct{})
+	var keys []string
+	for _, ch := range batch {
+		for _, key := range i.Keys(ch) {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	backoff := i.retryBackoff
+	for {
+		err := i.invalidate(ctx, keys)
+		if err == nil {
+			err = i.stream.Ack(ctx, batch...)
+		}
+		if err == nil {
+			return nil
+		}
+
+		logger.Warn("cdc#apply(%d changes) error(%v), retry in %s", len(batch), err, backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+}
+
+func (i *Invalidator) invalidate(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return i.cache.Invalidate(ctx, keys...)
+}
@@ -0,0 +1,151 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mgtv-tech/jetcache-go"
+	"github.com/mgtv-tech/jetcache-go/local"
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+func newCache(t *testing.T, opts ...cache.Option) cache.Cache {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	c := cache.New(append([]cache.Option{cache.WithRemote(remote.NewGoRedisV9Adapter(rdb)),
+		cache.WithLocal(local.NewFreeCache(local.MB, time.Minute))}, opts...)...)
+	t.Cleanup(c.Close)
+
+	return c
+}
+
+var rules = []Rule{
+	{Table: "user", Key: "user", IDs: Column("id")},
+	{Table: "user", Keys: func(ch Change) []string {
+		return []string{"user:list"}
+	}},
+}
+
+func TestInvalidator(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("keys", func(t *testing.T) {
+		i := New(newCache(t), NewChanStream(nil), rules)
+		keys := i.Keys(Change{Table: "user", Op: Update, Before: map[string]any{"id": 1}, After: map[string]any{"id": 2}})
+		assert.Equal(t, []string{"user:1", "user:2", "user:list"}, keys)
+		assert.Equal(t, []string{"user:1", "user:list"}, i.Keys(Change{Table: "user", Op: Delete, Before: map[string]any{"id": 1}}))
+		assert.Empty(t, i.Keys(Change{Table: "order", Op: Insert, After: map[string]any{"id": 1}}))
+	})
+
+	t.Run("chan stream", func(t *testing.T) {
+		var (
+			mu     sync.Mutex
+			events []*cache.Event
+		)
+		c := newCache(t, cache.WithSyncLocal(true), cache.WithEventHandler(func(event *cache.Event) {
+			mu.Lock()
+			events = append(events, event)
+			mu.Unlock()
+		}))
+		for _, key := range []string{"user:1", "user:2", "user:list", "order:1"} {
+			require.NoError(t, c.Set(ctx, key, cache.Value("value")))
+		}
+
+		changes := make(chan Change, 3)
+		changes <- Change{Table: "user", Op: Update, Before: map[string]any{"id": 1}, After: map[string]any{"id": 1}}
+		changes <- Change{Table: "user", Op: Delete, Before: map[string]any{"id": 2}}
+		changes <- Change{Table: "order", Op: Delete, Before: map[string]any{"id": 1}}
+		close(changes)
+		stream := NewChanStream(changes)
+
+		assert.NoError(t, New(c, stream, rules, WithFlushInterval(time.Second)).Run(ctx))
+		assert.Len(t, stream.Acked(), 3)
+		for _, key := range []string{"user:1", "user:2", "user:list"} {
+			assert.False(t, c.Exists(ctx, key), key)
+		}
+		assert.True(t, c.Exists(ctx, "order:1"))
+
+		require.NoError(t, c.(cache.Shutdowner).Shutdown(ctx))
+		var deleted []string
+		for _, event := range events {
+			if event.EventType == cache.EventTypeDelete {
+				deleted = append(deleted, event.Keys...)
+			}
+		}
+		assert.ElementsMatch(t, []string{"user:1", "user:2", "user:list"}, deleted)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		changes := make(chan Change, 1)
+		changes <- Change{Table: "user", Op: Delete, Before: map[string]any{"id": 1}}
+		close(changes)
+		stream := &failingAckStream{ChanStream: NewChanStream(changes), fails: 2}
+
+		assert.NoError(t, New(newCache(t), stream, rules, WithRetryBackoff(time.Millisecond)).Run(ctx))
+		assert.Len(t, stream.Acked(), 1)
+		assert.Equal(t, 3, stream.acks)
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		stream := NewChanStream(make(chan Change))
+		assert.ErrorIs(t, New(newCache(t), stream, rules).Run(ctx), context.DeadlineExceeded)
+	})
+}
+
+func TestFileStream(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path, checkpoint := filepath.Join(dir, "changes.jsonl"), filepath.Join(dir, "changes.offset")
+	require.NoError(t, os.WriteFile(path, []byte(`{"table":"user","op":"update","after":{"id":12345678901234567}}
+
+{"table":"user","op":"delete","before":{"id":2}}
+`), 0o644))
+
+	stream, err := NewFileStream(path, checkpoint)
+	require.NoError(t, err)
+	ch, err := stream.Next(ctx)
+	require.NoError(t, err)
+	i := New(newCache(t), stream, rules)
+	assert.Equal(t, []string{"user:12345678901234567", "user:list"}, i.Keys(ch))
+	require.NoError(t, stream.Ack(ctx, ch))
+	require.NoError(t, stream.Close())
+
+	// resumes after the acknowledged change.
+	stream, err = NewFileStream(path, checkpoint)
+	require.NoError(t, err)
+	defer stream.Close()
+	ch, err = stream.Next(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Delete, ch.Op)
+	_, err = stream.Next(ctx)
+	assert.True(t, errors.Is(err, io.EOF))
+}
+
+// failingAckStream fails the first acks.
+type failingAckStream struct {
+	*ChanStream
+	fails int
+	acks  int
+}
+
+func (s *failingAckStream) Ack(ctx context.Context, changes ...Change) error {
+	s.acks++
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("ack error")
+	}
+	return s.ChanStream.Ack(ctx, changes...)
+}
@@ -0,0 +1,150 @@
+package cdc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+var (
+	_ Stream = (*ChanStream)(nil)
+	_ Stream = (*FileStream)(nil)
+)
+
+type (
+	// ChanStream is a Stream of the changes sent to a channel, ending once the
+	// channel is closed. It records the acknowledged changes, see Acked.
+	ChanStream struct {
+		ch    <-chan Change
+		mu    sync.Mutex
+		acked []Change
+	}
+
+	// FileStream is a Stream of the changes stored as JSON lines in a file,
+	// such as an exported outbox, ending at the end of the file. The position
+	// of the last acknowledged change is stored in a checkpoint file, from
+	// which a new FileStream of the file resumes.
+	FileStream struct {
+		f          *os.File
+		r          *bufio.Reader
+		offset     int64 // offset of the next line.
+		checkpoint string
+	}
+)
+
+// NewChanStream returns a Stream of the changes sent to ch.
+func NewChanStream(ch <-chan Change) *ChanStream {
+	return &ChanStream{ch: ch}
+}
+
+func (s *ChanStream) Next(ctx context.Context) (Change, error) {
+	select {
+	case ch, ok := <-s.ch:
+		if !ok {
+			return Change{}, io.EOF
+		}
+		return ch, nil
+	case <-ctx.Done():
+		return Change{}, ctx.Err()
+	}
+}
+
+func (s *ChanStream) Ack(_ context.Context, changes ...Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.acked = append(s.acked, changes...)
+	return nil
+}
+
+// Acked returns the acknowledged changes.
+func (s *ChanStream) Acked() []Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Change(nil), s.acked...)
+}
+
+// NewFileStream returns a Stream of the changes stored in the file, resuming
+// after the position stored in the checkpoint file, if any.
+func NewFileStream(path, checkpoint string) (*FileStream, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &FileStream{f: f, checkpoint: checkpoint}
+	if b, err := os.ReadFile(checkpoint); err == nil {
+		if s.offset, err = strconv.ParseInt(string(bytes.TrimSpace(b)), 10, 64); err != nil {
+			_ = f.Close()
+			return nil, fmt.Errorf("cdc: invalid checkpoint %q: %w", checkpoint, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		_ = f.Close()
+		return nil, err
+	}
+	if _, err = f.Seek(s.offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	s.r = bufio.NewReader(f)
+
+	return s, nil
+}
+
+// Next returns the change of the next line, with the offset of the line after
+// it as Position. Empty lines are skipped.
+func (s *FileStream) Next(ctx context.Context) (Change, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return Change{}, err
+		}
+
+		line, err := s.r.ReadBytes('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+			return Change{}, err
+		}
+		start := s.offset
+		s.offset += int64(len(line))
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var ch Change
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		if err = dec.Decode(&ch); err != nil {
+			return Change{}, fmt.Errorf("cdc: invalid change at offset %d: %w", start, err)
+		}
+		ch.Position = strconv.FormatInt(s.offset, 10)
+
+		return ch, nil
+	}
+}
+
+// Ack stores the position of the last change in the checkpoint file.
+func (s *FileStream) Ack(_ context.Context, changes ...Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	tmp := s.checkpoint + ".tmp"
+	if err := os.WriteFile(tmp, []byte(changes[len(changes)-1].Position), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.checkpoint)
+}
+
+// Close closes the file.
+func (s *FileStream) Close() error {
+	return s.f.Close()
+}
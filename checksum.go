@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"hash/crc32"
+
+	"github.com/mgtv-tech/jetcache-go/logger"
+	"github.com/mgtv-tech/jetcache-go/stats"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+type (
+	// Checksum computes the integrity trailer appended to the remote values,
+	// see WithChecksum. Implementations must be safe for concurrent use.
+	Checksum interface {
+		// Size returns the length of the trailer.
+		Size() int
+		// Sum returns the trailer of the value stored under the key.
+		Sum(key string, value []byte) []byte
+	}
+
+	crc32Checksum struct{}
+
+	hmacChecksum struct {
+		secret []byte
+	}
+)
+
+// NewCRC32Checksum returns a Checksum detecting corrupted or foreign values
+// with a CRC-32C of the key and value.
+func NewCRC32Checksum() Checksum {
+	return crc32Checksum{}
+}
+
+// NewHMACChecksum returns a Checksum authenticating the values with a
+// HMAC-SHA256 of the key and value, so that services without the secret cannot
+// write values accepted by the cache.
+func NewHMACChecksum(secret []byte) Checksum {
+	return hmacChecksum{secret: secret}
+}
+
+func (crc32Checksum) Size() int {
+	return crc32.Size
+}
+
+func (crc32Checksum) Sum(key string, value []byte) []byte {
+	sum := crc32.Update(crc32.Checksum([]byte(key), crc32cTable), crc32cTable, value)
+	return binary.BigEndian.AppendUint32(nil, sum)
+}
+
+func (hmacChecksum) Size() int {
+	return sha256.Size
+}
+
+func (h hmacChecksum) Sum(key string, value []byte) []byte {
+	mac := hmac.New(sha256.New, h.secret)
+	_ = binary.Write(mac, binary.BigEndian, uint32(len(key)))
+	mac.Write([]byte(key))
+	mac.Write(value)
+	return mac.Sum(nil)
+}
+
+// seal appends the checksum trailer to the value written to the remote cache.
+func (c *jetCache) seal(key string, b []byte) []byte {
+	if c.checksum == nil {
+		return b
+	}
+
+	sealed := make([]byte, 0, len(b)+c.checksum.Size())
+	sealed = append(sealed, b...)
+	return append(sealed, c.checksum.Sum(key, b)...)
+}
+
+// unseal strips the checksum trailer of the value, without side effects.
+func (c *jetCache) unseal(key string, b []byte) ([]byte, bool) {
+	if c.checksum == nil {
+		return b, true
+	}
+
+	if n := len(b) - c.checksum.Size(); n >= 0 {
+		if subtle.ConstantTimeCompare(b[n:], c.checksum.Sum(key, b[:n])) == 1 {
+			return b[:n], true
+		}
+	}
+
+	return nil, false
+}
+
+// verify strips the checksum trailer of the value read from the remote cache.
+// A mismatch fires the corruption stats event, and evicts the key with
+// WithEvictCorrupted.
+func (c *jetCache) verify(ctx context.Context, key string, b []byte) ([]byte, bool) {
+	if b, ok := c.unseal(key, b); ok {
+		return b, true
+	}
+
+	if h, ok := c.statsHandler.(stats.CorruptHandler); ok {
+		h.IncrCorrupt()
+	}
+	if c.evictCorrupted {
+		if _, err := c.remote.Del(ctx, key); err != nil {
+			logger.Error("verify#c.remote.Del(%s) error(%v)", key, err)
+		}
+	}
+
+	return nil, false
+}
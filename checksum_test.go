@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+func TestChecksum(t *testing.T) {
+	for _, checksum := range []Checksum{NewCRC32Checksum(), NewHMACChecksum([]byte("secret"))} {
+		sum := checksum.Sum("key", []byte("value"))
+		assert.Len(t, sum, checksum.Size())
+		assert.Equal(t, sum, checksum.Sum("key", []byte("value")))
+		assert.NotEqual(t, sum, checksum.Sum("key2", []byte("value")))
+		assert.NotEqual(t, sum, checksum.Sum("key", []byte("value2")))
+	}
+	assert.NotEqual(t, NewHMACChecksum([]byte("secret")).Sum("key", nil), NewHMACChecksum([]byte("other")).Sum("key", nil))
+}
+
+func TestCacheChecksum(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRdb()
+	c := New(WithName("checksum"), WithRemote(remote.NewGoRedisV9Adapter(rdb)),
+		WithChecksum(NewHMACChecksum([]byte("secret"))), WithErrNotFound(errTestNotFound))
+	defer c.Close()
+
+	t.Run("set and get", func(t *testing.T) {
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		raw, err := rdb.Get(ctx, "key1").Result()
+		assert.NoError(t, err)
+		assert.Len(t, raw, len("value1")+32)
+
+		var val string
+		assert.NoError(t, c.Get(ctx, "key1", &val))
+		assert.Equal(t, "value1", val)
+
+		values, err := c.(Inspector).Peek(ctx, "key1")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(raw), values[0].Raw)
+	})
+
+	t.Run("not found placeholder", func(t *testing.T) {
+		var val string
+		err := c.Once(ctx, "key2", Value(&val), Do(func(context.Context) (any, error) {
+			return nil, errTestNotFound
+		}))
+		assert.ErrorIs(t, err, errTestNotFound)
+		assert.ErrorIs(t, c.Get(ctx, "key2", &val), errTestNotFound)
+
+		values, err := c.(Inspector).Peek(ctx, "key2")
+		assert.NoError(t, err)
+		assert.True(t, values[0].Placeholder)
+	})
+
+	t.Run("foreign value", func(t *testing.T) {
+		assert.NoError(t, rdb.Set(ctx, "key3", "foreign", time.Minute).Err())
+		corrupt := c.(Inspector).Stats().Corrupt
+
+		var val string
+		assert.ErrorIs(t, c.Get(ctx, "key3", &val), ErrCacheMiss)
+		assert.Equal(t, corrupt+1, c.(Inspector).Stats().Corrupt)
+		assert.Equal(t, int64(1), rdb.Exists(ctx, "key3").Val())
+
+		err := c.Once(ctx, "key3", Value(&val), Do(func(context.Context) (any, error) {
+			return "loaded", nil
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, "loaded", val)
+		assert.NoError(t, c.Get(ctx, "key3", &val))
+	})
+
+	t.Run("mget", func(t *testing.T) {
+		cacheT := NewT[int, string](c)
+		assert.NoError(t, rdb.Set(ctx, "mget:2", "foreign", time.Minute).Err())
+		fn := func(_ context.Context, ids []int) (map[int]string, error) {
+			ret := make(map[int]string, len(ids))
+			for _, id := range ids {
+				if id != 3 {
+					ret[id] = "loaded"
+				}
+			}
+			return ret, nil
+		}
+
+		ret, err := cacheT.MGetWithErr(ctx, "mget", []int{1, 2, 3}, fn)
+		assert.NoError(t, err)
+		assert.Equal(t, map[int]string{1: "loaded", 2: "loaded"}, ret)
+
+		ret, err = cacheT.MGetWithErr(ctx, "mget", []int{1, 2, 3}, func(context.Context, []int) (map[int]string, error) {
+			return nil, errors.New("not called")
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[int]string{1: "loaded", 2: "loaded"}, ret)
+	})
+}
+
+func TestCacheEvictCorrupted(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRdb()
+	c := New(WithName("evictCorrupted"), WithRemote(remote.NewGoRedisV9Adapter(rdb)),
+		WithChecksum(NewCRC32Checksum()), WithEvictCorrupted(true))
+	defer c.Close()
+
+	assert.NoError(t, rdb.Set(ctx, "key1", "foreign value", time.Minute).Err())
+	var val string
+	assert.ErrorIs(t, c.Get(ctx, "key1", &val), ErrCacheMiss)
+	assert.Equal(t, uint64(1), c.(Inspector).Stats().Corrupt)
+	assert.Equal(t, int64(0), rdb.Exists(ctx, "key1").Val())
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+// Incr atomically adds delta to the counter of the key and returns the new
+// value. Counters are stored as decimal strings, without checksum trailer.
+// With a remote cache, the increment is atomic on the remote, which must
+// implement remote.Counter, and a missing key is created expiring after the
+// ttl, or the remoteExpiry if the ttl is 0. It returns ErrCounterChecksum with
+// WithChecksum, which would report the remote counters as corrupted. The new value is written back to
+// the local cache. Without remote cache, the counters are kept in the local
+// cache, atomic within this instance only.
+func (c *jetCache) Incr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	if c.remote == nil {
+		if c.local == nil {
+			return 0, ErrRemoteLocalBothNil
+		}
+		return c.incrLocal(key, delta)
+	}
+
+	if c.checksum != nil {
+		return 0, ErrCounterChecksum
+	}
+	counter, ok := c.remote.(remote.Counter)
+	if !ok {
+		return 0, ErrCounterUnsupported
+	}
+	if ttl == 0 {
+		ttl = c.remoteExpiry
+	}
+
+	val, err := counter.IncrBy(ctx, key, delta, ttl)
+	if err != nil {
+		return 0, fmt.Errorf("Incr#counter.IncrBy(%s) error(%v)", key, err)
+	}
+
+	c.delLocalTiers(key)
+	if c.local != nil {
+		c.local.Set(key, strconv.AppendInt(nil, val, 10))
+	}
+	c.send(EventTypeSet, key)
+
+	return val, nil
+}
+
+// Decr atomically subtracts delta from the counter of the key, see Incr.
+func (c *jetCache) Decr(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return c.Incr(ctx, key, -delta, ttl)
+}
+
+// incrLocal is the in-memory fallback of Incr for the local only caches.
+func (c *jetCache) incrLocal(key string, delta int64) (int64, error) {
+	c.localMu.Lock()
+	defer c.localMu.Unlock()
+
+	var val int64
+	if b, ok := c.local.Get(key); ok && !c.isNotFoundPlaceholder(b) {
+		var err error
+		if val, err = strconv.ParseInt(string(b), 10, 64); err != nil {
+			return 0, fmt.Errorf("Incr#strconv.ParseInt(%s) error(%v)", key, err)
+		}
+	}
+	val += delta
+
+	c.delLocalTiers(key)
+	c.local.Set(key, strconv.AppendInt(nil, val, 10))
+	c.send(EventTypeSet, key)
+
+	return val, nil
+}
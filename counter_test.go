@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+func TestIncr(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("remote", func(t *testing.T) {
+		rdb := newRdb()
+		c := New(WithName("incr"), WithRemote(remote.NewGoRedisV9Adapter(rdb)), WithLocal(localNew(freeCache)),
+			WithRemoteExpiry(time.Hour))
+		defer c.Close()
+
+		val, err := c.(Counter).Incr(ctx, "views", 2, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), val)
+		assert.Equal(t, time.Hour, rdb.TTL(ctx, "views").Val())
+
+		val, err = c.(Counter).Decr(ctx, "views", 5, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(-3), val)
+		assert.Equal(t, time.Hour, rdb.TTL(ctx, "views").Val())
+
+		// the new value is written back to the local cache.
+		var s string
+		assert.NoError(t, c.Get(ctx, "views", &s))
+		assert.Equal(t, "-3", s)
+		peek, err := c.(Inspector).Peek(ctx, "views")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("-3"), peek[0].Raw)
+
+		assert.NoError(t, c.Set(ctx, "text", Value("value")))
+		_, err = c.(Counter).Incr(ctx, "text", 1, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		c := New(WithName("incrConcurrent"), WithRemote(remote.NewGoRedisV9Adapter(newRdb())))
+		defer c.Close()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = c.(Counter).Incr(ctx, "hits", 1, time.Minute)
+			}()
+		}
+		wg.Wait()
+
+		val, err := c.(Counter).Incr(ctx, "hits", 0, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(50), val)
+	})
+
+	t.Run("local only", func(t *testing.T) {
+		c := New(WithName("incrLocal"), WithLocal(localNew(freeCache)))
+		defer c.Close()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = c.(Counter).Incr(ctx, "hits", 2, 0)
+			}()
+		}
+		wg.Wait()
+
+		val, err := c.(Counter).Decr(ctx, "hits", 1, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(99), val)
+
+		assert.NoError(t, c.Set(ctx, "text", Value("value")))
+		_, err = c.(Counter).Incr(ctx, "text", 1, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("checksum", func(t *testing.T) {
+		rdb := newRdb()
+		c := New(WithName("incrChecksum"), WithRemote(remote.NewGoRedisV9Adapter(rdb)), WithLocal(localNew(freeCache)),
+			WithChecksum(NewCRC32Checksum()), WithEvictCorrupted(true))
+		defer c.Close()
+
+		_, err := c.(Counter).Incr(ctx, "hits", 1, 0)
+		assert.ErrorIs(t, err, ErrCounterChecksum)
+		assert.Zero(t, rdb.Exists(ctx, "hits").Val())
+
+		// the checksum only covers the remote values.
+		localC := New(WithName("incrChecksumLocal"), WithLocal(localNew(freeCache)), WithChecksum(NewCRC32Checksum()))
+		defer localC.Close()
+		val, err := localC.(Counter).Incr(ctx, "hits", 1, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), val)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		c := New(WithName("incrUnsupported"), WithRemote(&failingDelRemote{Remote: remote.NewGoRedisV9Adapter(newRdb())}))
+		defer c.Close()
+
+		_, err := c.(Counter).Incr(ctx, "hits", 1, 0)
+		assert.ErrorIs(t, err, ErrCounterUnsupported)
+
+		_, err = New(WithName("incrNil")).(Counter).Incr(ctx, "hits", 1, 0)
+		assert.ErrorIs(t, err, ErrRemoteLocalBothNil)
+
+		rmt, err := remote.NewEncryptedRemote(remote.NewGoRedisV9Adapter(newRdb()),
+			remote.Key{ID: "k1", Secret: make([]byte, 32)})
+		assert.NoError(t, err)
+		encrypted := New(WithName("incrEncrypted"), WithRemote(rmt))
+		defer encrypted.Close()
+		_, err = encrypted.(Counter).Incr(ctx, "hits", 1, 0)
+		assert.ErrorContains(t, err, remote.ErrEncryptedCounter.Error())
+	})
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go/logger"
+)
+
+const (
+	defaultDeleteRetries      = 3
+	defaultDeleteRetryBackoff = 100 * time.Millisecond
+)
+
+// delayedDelete is a scheduled delete of a key, see DeleteWithDelay.
+type delayedDelete struct {
+	key     string
+	attempt int // failed attempts before this one.
+	timer   *time.Timer
+}
+
+// DeleteWithDelay implements the delayed double delete: it deletes the key
+// now, and again after the delay, once the data source replicas caught up
+// with the update. Failed deletes are retried with an exponential backoff.
+func (c *jetCache) DeleteWithDelay(ctx context.Context, key string, delay time.Duration) error {
+	err := c.del(ctx, key)
+	if err != nil {
+		c.scheduleDelete(key, c.deleteRetryBackoff, 1)
+	}
+	c.scheduleDelete(key, delay, 0)
+
+	return err
+}
+
+// scheduleDelete deletes the key after the delay, unless the cache is closed.
+func (c *jetCache) scheduleDelete(key string, delay time.Duration, attempt int) bool {
+	c.deletesMu.Lock()
+	defer c.deletesMu.Unlock()
+
+	if c.deletesClosed {
+		return false
+	}
+
+	d := &delayedDelete{key: key, attempt: attempt}
+	d.timer = time.AfterFunc(delay, func() {
+		if c.takeDelete(d) {
+			defer c.deletesWg.Done()
+			c.runDelete(d.key, d.attempt)
+		}
+	})
+	c.deletes[d] = struct{}{}
+
+	return true
+}
+
+// takeDelete removes the scheduled delete, so that it runs once, either by
+// its timer or by flushDeletes.
+func (c *jetCache) takeDelete(d *delayedDelete) bool {
+	c.deletesMu.Lock()
+	defer c.deletesMu.Unlock()
+
+	if _, ok := c.deletes[d]; !ok {
+		return false
+	}
+	delete(c.deletes, d)
+	c.deletesWg.Add(1)
+
+	return true
+}
+
+// runDelete deletes the key, and schedules a retry on failure. Once the cache
+// is closed, the retries run inline.
+func (c *jetCache) runDelete(key string, attempt int) {
+	for {
+		err := c.del(context.Background(), key)
+		if err == nil {
+			return
+		}
+		if attempt >= c.deleteRetries {
+			logger.Error("delayedDelete(%s) error(%v), dropped after %d retries", key, err, c.deleteRetries)
+			return
+		}
+
+		backoff := c.deleteRetryBackoff << attempt
+		attempt++
+		if c.scheduleDelete(key, backoff, attempt) {
+			return
+		}
+		time.Sleep(backoff)
+	}
+}
+
+// flushDeletes stops scheduling deletes and runs the scheduled ones now, then
+// waits for them, or returns ctx.Err() if ctx is done first.
+func (c *jetCache) flushDeletes(ctx context.Context) error {
+	c.deletesMu.Lock()
+	c.deletesClosed = true
+	pending := c.deletes
+	c.deletes = make(map[*delayedDelete]struct{})
+	c.deletesWg.Add(len(pending))
+	c.deletesMu.Unlock()
+
+	for d := range pending {
+		d := d
+		d.timer.Stop()
+		go func() {
+			defer c.deletesWg.Done()
+			c.runDelete(d.key, d.attempt)
+		}()
+	}
+
+	return wait(ctx, &c.deletesWg)
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+// failingDelRemote fails the first Del calls.
+type failingDelRemote struct {
+	remote.Remote
+	mu    sync.Mutex
+	fails int
+	dels  int
+}
+
+func (r *failingDelRemote) Del(ctx context.Context, key string) (int64, error) {
+	r.mu.Lock()
+	r.dels++
+	fail := r.fails > 0
+	if fail {
+		r.fails--
+	}
+	r.mu.Unlock()
+
+	if fail {
+		return 0, errors.New("del error")
+	}
+	return r.Remote.Del(ctx, key)
+}
+
+func (r *failingDelRemote) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.dels
+}
+
+func TestDeleteWithDelay(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("double delete", func(t *testing.T) {
+		rmt := &failingDelRemote{Remote: remote.NewGoRedisV9Adapter(newRdb())}
+		var events sync.Map
+		c := New(WithName("doubleDelete"), WithRemote(rmt), WithLocal(localNew(freeCache)), WithSyncLocal(true),
+			WithEventHandler(func(event *Event) {
+				if event.EventType == EventTypeDelete {
+					n, _ := events.LoadOrStore(event.Keys[0], new(int))
+					*n.(*int)++
+				}
+			}))
+
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		assert.NoError(t, c.(Invalidator).DeleteWithDelay(ctx, "key1", 50*time.Millisecond))
+		var val string
+		assert.ErrorIs(t, c.Get(ctx, "key1", &val), ErrCacheMiss)
+
+		// a stale read cached again before the replicas caught up.
+		assert.NoError(t, c.Set(ctx, "key1", Value("stale")))
+		time.Sleep(100 * time.Millisecond)
+		assert.ErrorIs(t, c.Get(ctx, "key1", &val), ErrCacheMiss)
+		assert.Equal(t, 2, rmt.count())
+
+		assert.NoError(t, c.(Shutdowner).Shutdown(ctx))
+		n, _ := events.Load("key1")
+		assert.Equal(t, 2, *n.(*int))
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		rmt := &failingDelRemote{Remote: remote.NewGoRedisV9Adapter(newRdb()), fails: 2}
+		c := New(WithName("deleteRetry"), WithRemote(rmt), WithDeleteRetryBackoff(time.Millisecond))
+
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		assert.Error(t, c.(Invalidator).DeleteWithDelay(ctx, "key1", time.Hour))
+		time.Sleep(50 * time.Millisecond)
+		var val string
+		assert.ErrorIs(t, c.Get(ctx, "key1", &val), ErrCacheMiss)
+		assert.Equal(t, 3, rmt.count())
+
+		// the follow-up delete is run by Close.
+		c.Close()
+		assert.Equal(t, 4, rmt.count())
+		assert.NoError(t, c.(Invalidator).DeleteWithDelay(ctx, "key1", time.Hour))
+		assert.Equal(t, 5, rmt.count())
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		rmt := &failingDelRemote{Remote: remote.NewGoRedisV9Adapter(newRdb()), fails: 10}
+		c := New(WithName("deleteDrop"), WithRemote(rmt), WithDeleteRetries(1), WithDeleteRetryBackoff(time.Millisecond))
+
+		assert.Error(t, c.(Invalidator).DeleteWithDelay(ctx, "key1", time.Millisecond))
+		time.Sleep(50 * time.Millisecond)
+		c.Close()
+		// the immediate delete and its retry, the follow-up delete and its retry.
+		assert.Equal(t, 4, rmt.count())
+	})
+}
+
+func TestInvalidate(t *testing.T) {
+	ctx := context.Background()
+	writer := newMockWriter()
+	c := New(WithName("invalidate"), WithRemote(remote.NewGoRedisV9Adapter(newRdb())), WithLocal(localNew(freeCache)),
+		WithWriter(writer))
+	defer c.Close()
+
+	assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+	assert.NoError(t, c.Set(ctx, "key2", Value("value2")))
+	assert.NoError(t, c.(Invalidator).Invalidate(ctx, "key1", "key2", "key3"))
+	assert.False(t, c.Exists(ctx, "key1"))
+	assert.False(t, c.Exists(ctx, "key2"))
+	assert.Equal(t, []string{"key1", "key2"}, writer.writes)
+
+	rmt := &failingDelRemote{Remote: remote.NewGoRedisV9Adapter(newRdb()), fails: 1}
+	failC := New(WithName("invalidateErr"), WithRemote(rmt))
+	defer failC.Close()
+	assert.Error(t, failC.(Invalidator).Invalidate(ctx, "key1", "key2"))
+	assert.Equal(t, 2, rmt.count())
+}
@@ -0,0 +1,232 @@
+// Package compress provides a Codec wrapper compressing the output of any
+// registered Codec.
+//
+// Compressed payloads start with the encoding.FormatCompress header followed
+// by the Algorithm. Payloads without the header, such as values written before
+// the wrapper was enabled, are decoded by the legacy Codec, and so are the
+// legacy payloads starting with the header bytes by chance, which fail to
+// decompress.
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/mgtv-tech/jetcache-go/encoding"
+)
+
+// Algorithm is the compression algorithm recorded in the header.
+type Algorithm byte
+
+const (
+	None Algorithm = iota
+	S2
+	Zstd
+	Gzip
+)
+
+const (
+	headerLen        = encoding.HeaderLen + 1 // the algorithm follows the header.
+	defaultThreshold = 64
+)
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+type (
+	// Option defines the method to customize the compression codec.
+	Option func(o *codec)
+
+	// codec is a Codec implementation compressing the output of the inner Codec.
+	codec struct {
+		inner     encoding.Codec
+		legacy    encoding.Codec
+		algorithm Algorithm
+		threshold int
+		name      string
+	}
+)
+
+// WithAlgorithm sets the compression algorithm, defaulting to S2.
+func WithAlgorithm(algorithm Algorithm) Option {
+	return func(o *codec) {
+		o.algorithm = algorithm
+	}
+}
+
+// WithThreshold sets the minimum size in bytes of the encoded values to
+// compress, defaulting to 64. Smaller values are stored with the None algorithm.
+func WithThreshold(threshold int) Option {
+	return func(o *codec) {
+		o.threshold = threshold
+	}
+}
+
+// WithLegacy sets the Codec decoding the payloads without header, defaulting
+// to the inner Codec. For example, the msgpack codec reads the values written
+// by the msgpack codec before wrapping its plain variant.
+func WithLegacy(legacy encoding.Codec) Option {
+	return func(o *codec) {
+		o.legacy = legacy
+	}
+}
+
+// WithName sets the name of the codec, defaulting to "<inner>+<algorithm>",
+// such as "json+zstd".
+func WithName(name string) Option {
+	return func(o *codec) {
+		o.name = name
+	}
+}
+
+// New returns a Codec compressing the output of the inner Codec. Register it
+// with encoding.RegisterCodec to use it with cache.WithCodec.
+func New(inner encoding.Codec, opts ...Option) encoding.Codec {
+	if inner == nil {
+		panic("compress: nil inner Codec")
+	}
+
+	c := &codec{
+		inner:     inner,
+		legacy:    inner,
+		algorithm: S2,
+		threshold: defaultThreshold,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.algorithm > Gzip {
+		panic(fmt.Sprintf("compress: unknown algorithm %d", c.algorithm))
+	}
+	if c.name == "" {
+		c.name = inner.Name() + "+" + c.algorithm.String()
+	}
+
+	return c
+}
+
+func (c *codec) Marshal(v any) ([]byte, error) {
+	data, err := c.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	algorithm := c.algorithm
+	if len(data) < c.threshold {
+		algorithm = None
+	}
+
+	return Compress(algorithm, data)
+}
+
+func (c *codec) Unmarshal(data []byte, v any) error {
+	if !HasHeader(data) {
+		return c.legacy.Unmarshal(data, v)
+	}
+
+	b, err := Decompress(data)
+	if err != nil {
+		if c.legacy.Unmarshal(data, v) == nil {
+			return nil
+		}
+		return err
+	}
+
+	return c.inner.Unmarshal(b, v)
+}
+
+func (c *codec) Name() string {
+	return c.name
+}
+
+// HasHeader reports whether the data starts with a compression header.
+func HasHeader(data []byte) bool {
+	return len(data) >= headerLen && encoding.HasHeader(data, encoding.FormatCompress) &&
+		Algorithm(data[headerLen-1]) <= Gzip
+}
+
+// Compress returns the data compressed by the algorithm, prefixed by the header.
+func Compress(algorithm Algorithm, data []byte) ([]byte, error) {
+	header := append(encoding.AppendHeader(make([]byte, 0, headerLen), encoding.FormatCompress), byte(algorithm))
+
+	switch algorithm {
+	case None:
+		return append(header, data...), nil
+	case S2:
+		b := make([]byte, headerLen+s2.MaxEncodedLen(len(data)))
+		copy(b, header)
+		return b[:headerLen+len(s2.Encode(b[headerLen:], data))], nil
+	case Zstd:
+		initZstd()
+		return zstdEncoder.EncodeAll(data, header), nil
+	case Gzip:
+		buf := bytes.NewBuffer(header)
+		w := gzip.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("compress: unknown algorithm %d", algorithm)
+	}
+}
+
+// Decompress returns the data without header, decompressed by the algorithm
+// recorded in the header.
+func Decompress(data []byte) ([]byte, error) {
+	if !HasHeader(data) {
+		return nil, fmt.Errorf("compress: missing header")
+	}
+
+	algorithm, payload := Algorithm(data[headerLen-1]), data[headerLen:]
+	switch algorithm {
+	case None:
+		return payload, nil
+	case S2:
+		return s2.Decode(nil, payload)
+	case Zstd:
+		initZstd()
+		return zstdDecoder.DecodeAll(payload, nil)
+	default:
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	}
+}
+
+func (a Algorithm) String() string {
+	switch a {
+	case None:
+		return "none"
+	case S2:
+		return "s2"
+	case Zstd:
+		return "zstd"
+	case Gzip:
+		return "gzip"
+	default:
+		return fmt.Sprintf("Algorithm(%d)", a)
+	}
+}
+
+func initZstd() {
+	zstdOnce.Do(func() {
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+}
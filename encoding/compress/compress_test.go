@@ -0,0 +1,121 @@
+package compress
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/mgtv-tech/jetcache-go/encoding"
+	_ "github.com/mgtv-tech/jetcache-go/encoding/json"
+	"github.com/mgtv-tech/jetcache-go/encoding/msgpack"
+	"github.com/mgtv-tech/jetcache-go/encoding/proto"
+)
+
+type testMessage struct {
+	Id    int64
+	Name  string
+	Hobby []string
+}
+
+func TestCompressCodec(t *testing.T) {
+	tests := []testMessage{
+		{Id: 1, Name: "jetcache-go"},
+		{Id: 1, Name: strings.Repeat("my very large string", 10), Hobby: []string{"study", "eat", "play"}},
+	}
+
+	for _, algorithm := range []Algorithm{None, S2, Zstd, Gzip} {
+		c := New(encoding.GetCodec("json"), WithAlgorithm(algorithm))
+		assert.Equal(t, "json+"+algorithm.String(), c.Name())
+
+		for i, v := range tests {
+			data, err := c.Marshal(&v)
+			assert.NoError(t, err)
+			assert.True(t, HasHeader(data))
+			if i == 0 {
+				assert.Equal(t, None, Algorithm(data[headerLen-1]))
+			} else {
+				assert.Equal(t, algorithm, Algorithm(data[headerLen-1]))
+			}
+
+			var res testMessage
+			assert.NoError(t, c.Unmarshal(data, &res))
+			assert.Equal(t, v, res)
+		}
+	}
+}
+
+func TestCompressCodecThreshold(t *testing.T) {
+	c := New(encoding.GetCodec("json"), WithAlgorithm(Zstd), WithThreshold(0), WithName("json-zstd"))
+	assert.Equal(t, "json-zstd", c.Name())
+
+	data, err := c.Marshal(&testMessage{Id: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, Zstd, Algorithm(data[headerLen-1]))
+}
+
+func TestCompressCodecLegacy(t *testing.T) {
+	legacy := encoding.GetCodec(msgpack.Name)
+	c := New(encoding.GetCodec(msgpack.PlainName), WithLegacy(legacy))
+	assert.Equal(t, "msgpack-plain+s2", c.Name())
+
+	for _, v := range []testMessage{
+		{Id: 1, Name: "jetcache-go"},
+		{Id: 1, Name: strings.Repeat("my very large string", 10)},
+	} {
+		data, err := legacy.Marshal(&v)
+		assert.NoError(t, err)
+		assert.False(t, HasHeader(data))
+
+		var res testMessage
+		assert.NoError(t, c.Unmarshal(data, &res))
+		assert.Equal(t, v, res)
+	}
+
+	// json values written without the wrapper are decoded by the inner codec.
+	jsonCodec := New(encoding.GetCodec("json"))
+	var res testMessage
+	assert.NoError(t, jsonCodec.Unmarshal([]byte(`{"Id":2}`), &res))
+	assert.Equal(t, int64(2), res.Id)
+}
+
+func TestCompressCodecLegacyCollision(t *testing.T) {
+	pb := encoding.GetCodec(proto.Name)
+	c := New(pb)
+	value, err := pb.Marshal(wrapperspb.String("jetcache-go"))
+	assert.NoError(t, err)
+
+	for _, prefix := range [][]byte{
+		// field 24, fixed64: the tag 0xc1 0x01 starts with the magic byte.
+		{0xc1, 0x01, 1, 2, 3, 4, 5, 6, 7, 8},
+		// field 1704, fixed64: the tag and the value start with a full header.
+		{0xc1, 'j', 'c', encoding.FormatCompress, byte(S2), 0xff, 0xff, 0xff, 0xff, 0xff},
+	} {
+		data := append(prefix, value...)
+		assert.Equal(t, prefix[1] == 'j', HasHeader(data))
+
+		var res wrapperspb.StringValue
+		assert.NoError(t, c.Unmarshal(data, &res))
+		assert.Equal(t, "jetcache-go", res.GetValue())
+	}
+}
+
+func TestCompressCodecError(t *testing.T) {
+	assert.Panics(t, func() { New(nil) })
+	assert.Panics(t, func() { New(encoding.GetCodec("json"), WithAlgorithm(Gzip+1)) })
+	assert.Equal(t, "Algorithm(4)", (Gzip + 1).String())
+
+	_, err := Compress(Gzip+1, nil)
+	assert.Error(t, err)
+	_, err = Decompress([]byte("{}"))
+	assert.Error(t, err)
+
+	c := New(encoding.GetCodec("json"))
+	for _, algorithm := range []Algorithm{S2, Zstd, Gzip} {
+		var res testMessage
+		assert.Error(t, c.Unmarshal(append(encoding.AppendHeader(nil, encoding.FormatCompress), byte(algorithm), 0xff, 0xff), &res))
+	}
+	_, err = c.Marshal(func() {})
+	assert.Error(t, err)
+}
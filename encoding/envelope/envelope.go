@@ -0,0 +1,211 @@
+// Package envelope provides a Codec wrapper recording the codec name, a
+// schema version and the creation time next to each value, so that values
+// stay readable across codec switches and schema changes.
+//
+// Enveloped payloads start with the encoding.FormatEnvelope header. Payloads
+// without the header, such as values written before the wrapper was enabled,
+// are decoded by the legacy Codec, and so are the legacy payloads starting
+// with the header bytes by chance, which fail to parse as an envelope.
+package envelope
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go/encoding"
+)
+
+const headerLen = encoding.HeaderLen
+
+var (
+	// ErrInvalid is returned when the envelope header is malformed.
+	ErrInvalid = errors.New("envelope: invalid envelope")
+	// ErrUnknownCodec is returned when the codec recorded in the envelope is not registered.
+	ErrUnknownCodec = errors.New("envelope: unknown codec")
+)
+
+type (
+	// Envelope is a decoded envelope.
+	Envelope struct {
+		Codec   string    // Name of the codec which encoded Data.
+		Version uint32    // Schema version set by WithVersion when the value was written.
+		Created time.Time // Creation time, in milliseconds.
+		Data    []byte    // Value encoded by Codec.
+	}
+
+	// UpgradeFunc decodes a value written with an older, or newer, schema
+	// version into v. Returning an error fails the read, so that Once reloads
+	// the value.
+	UpgradeFunc func(e *Envelope, v any) error
+
+	// Option defines the method to customize the envelope codec.
+	Option func(o *codec)
+
+	// codec is a Codec implementation wrapping the output of the inner Codec
+	// in an envelope.
+	codec struct {
+		inner    encoding.Codec
+		legacy   encoding.Codec
+		version  uint32
+		upgrades map[uint32]UpgradeFunc
+		name     string
+	}
+)
+
+// WithVersion sets the schema version recorded in the written envelopes,
+// defaulting to 0.
+func WithVersion(version uint32) Option {
+	return func(o *codec) {
+		o.version = version
+	}
+}
+
+// WithUpgrade registers the hook decoding the envelopes of the schema version.
+// Envelopes of the current version, or of versions without hook, are decoded
+// by their recorded codec.
+func WithUpgrade(version uint32, fn UpgradeFunc) Option {
+	return func(o *codec) {
+		o.upgrades[version] = fn
+	}
+}
+
+// WithLegacy sets the Codec decoding the payloads without envelope, defaulting
+// to the inner Codec.
+func WithLegacy(legacy encoding.Codec) Option {
+	return func(o *codec) {
+		o.legacy = legacy
+	}
+}
+
+// WithName sets the name of the codec, defaulting to "<inner>+envelope".
+func WithName(name string) Option {
+	return func(o *codec) {
+		o.name = name
+	}
+}
+
+// New returns a Codec wrapping the output of the inner Codec in an envelope.
+// The inner Codec must be registered, so that envelopes written by it stay
+// readable after switching to another inner Codec. Register the returned Codec
+// with encoding.RegisterCodec to use it with cache.WithCodec.
+func New(inner encoding.Codec, opts ...Option) encoding.Codec {
+	if inner == nil {
+		panic("envelope: nil inner Codec")
+	}
+
+	c := &codec{
+		inner:    inner,
+		legacy:   inner,
+		upgrades: make(map[uint32]UpgradeFunc),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.name == "" {
+		c.name = inner.Name() + "+envelope"
+	}
+
+	return c
+}
+
+func (c *codec) Marshal(v any) ([]byte, error) {
+	data, err := c.inner.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return Marshal(&Envelope{
+		Codec:   c.inner.Name(),
+		Version: c.version,
+		Created: time.Now(),
+		Data:    data,
+	}), nil
+}
+
+func (c *codec) Unmarshal(data []byte, v any) error {
+	if !HasHeader(data) {
+		return c.legacy.Unmarshal(data, v)
+	}
+
+	e, err := Unmarshal(data)
+	if err != nil {
+		if c.legacy.Unmarshal(data, v) == nil {
+			return nil
+		}
+		return err
+	}
+	if e.Version != c.version {
+		if fn, ok := c.upgrades[e.Version]; ok {
+			return fn(e, v)
+		}
+	}
+
+	return e.Unmarshal(v)
+}
+
+func (c *codec) Name() string {
+	return c.name
+}
+
+// Unmarshal decodes the Data of the envelope with its recorded codec.
+func (e *Envelope) Unmarshal(v any) error {
+	codec := encoding.GetCodec(e.Codec)
+	if codec == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownCodec, e.Codec)
+	}
+
+	return codec.Unmarshal(e.Data, v)
+}
+
+// HasHeader reports whether the data starts with an envelope header.
+func HasHeader(data []byte) bool {
+	return encoding.HasHeader(data, encoding.FormatEnvelope)
+}
+
+// Marshal returns the wire format of the envelope.
+func Marshal(e *Envelope) []byte {
+	b := make([]byte, 0, headerLen+3*binary.MaxVarintLen64+len(e.Codec)+len(e.Data))
+	b = encoding.AppendHeader(b, encoding.FormatEnvelope)
+	b = binary.AppendUvarint(b, uint64(len(e.Codec)))
+	b = append(b, e.Codec...)
+	b = binary.AppendUvarint(b, uint64(e.Version))
+	b = binary.AppendVarint(b, e.Created.UnixMilli())
+
+	return append(b, e.Data...)
+}
+
+// Unmarshal parses the wire format of an envelope. The Data of the returned
+// envelope aliases data.
+func Unmarshal(data []byte) (*Envelope, error) {
+	if !HasHeader(data) {
+		return nil, ErrInvalid
+	}
+	data = data[headerLen:]
+
+	n, l := binary.Uvarint(data)
+	if l <= 0 || n > uint64(len(data)-l) {
+		return nil, ErrInvalid
+	}
+	codec := string(data[l : l+int(n)])
+	data = data[l+int(n):]
+
+	version, l := binary.Uvarint(data)
+	if l <= 0 || version > uint64(^uint32(0)) {
+		return nil, ErrInvalid
+	}
+	data = data[l:]
+
+	created, l := binary.Varint(data)
+	if l <= 0 {
+		return nil, ErrInvalid
+	}
+
+	return &Envelope{
+		Codec:   codec,
+		Version: uint32(version),
+		Created: time.UnixMilli(created),
+		Data:    data[l:],
+	}, nil
+}
@@ -0,0 +1,164 @@
+package envelope
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/mgtv-tech/jetcache-go/encoding"
+	"github.com/mgtv-tech/jetcache-go/encoding/compress"
+	_ "github.com/mgtv-tech/jetcache-go/encoding/json"
+	"github.com/mgtv-tech/jetcache-go/encoding/msgpack"
+	"github.com/mgtv-tech/jetcache-go/encoding/proto"
+	_ "github.com/mgtv-tech/jetcache-go/encoding/sonic"
+)
+
+type (
+	userV1 struct {
+		Id   int64
+		Name string
+	}
+
+	userV2 struct {
+		Id        int64
+		FirstName string
+		LastName  string
+	}
+)
+
+func TestEnvelopeCodec(t *testing.T) {
+	c := New(encoding.GetCodec(msgpack.Name), WithVersion(1))
+	assert.Equal(t, "msgpack+envelope", c.Name())
+
+	start := time.Now().Truncate(time.Millisecond)
+	data, err := c.Marshal(&userV1{Id: 1, Name: "jetcache-go"})
+	assert.NoError(t, err)
+	assert.True(t, HasHeader(data))
+	assert.False(t, compress.HasHeader(data))
+
+	e, err := Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, msgpack.Name, e.Codec)
+	assert.Equal(t, uint32(1), e.Version)
+	assert.False(t, e.Created.Before(start))
+
+	var res userV1
+	assert.NoError(t, c.Unmarshal(data, &res))
+	assert.Equal(t, userV1{Id: 1, Name: "jetcache-go"}, res)
+}
+
+func TestEnvelopeCodecMigration(t *testing.T) {
+	old := New(encoding.GetCodec(msgpack.Name), WithVersion(1))
+	data, err := old.Marshal(&userV1{Id: 1, Name: "jet cache"})
+	assert.NoError(t, err)
+
+	t.Run("codec switch", func(t *testing.T) {
+		c := New(encoding.GetCodec("sonic"), WithVersion(1))
+		var res userV1
+		assert.NoError(t, c.Unmarshal(data, &res))
+		assert.Equal(t, userV1{Id: 1, Name: "jet cache"}, res)
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		c := New(encoding.GetCodec("sonic"), WithVersion(2), WithUpgrade(1, func(e *Envelope, v any) error {
+			var u userV1
+			if err := e.Unmarshal(&u); err != nil {
+				return err
+			}
+			*v.(*userV2) = userV2{Id: u.Id, FirstName: "jet", LastName: "cache"}
+			return nil
+		}))
+
+		var res userV2
+		assert.NoError(t, c.Unmarshal(data, &res))
+		assert.Equal(t, userV2{Id: 1, FirstName: "jet", LastName: "cache"}, res)
+
+		data2, err := c.Marshal(&res)
+		assert.NoError(t, err)
+		res = userV2{}
+		assert.NoError(t, c.Unmarshal(data2, &res))
+		assert.Equal(t, userV2{Id: 1, FirstName: "jet", LastName: "cache"}, res)
+	})
+
+	t.Run("upgrade error", func(t *testing.T) {
+		errStale := errors.New("stale")
+		c := New(encoding.GetCodec("json"), WithVersion(2), WithUpgrade(1, func(*Envelope, any) error {
+			return errStale
+		}))
+		var res userV2
+		assert.ErrorIs(t, c.Unmarshal(data, &res), errStale)
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		legacy, err := encoding.GetCodec(msgpack.Name).Marshal(&userV1{Id: 2})
+		assert.NoError(t, err)
+
+		c := New(encoding.GetCodec("json"), WithLegacy(encoding.GetCodec(msgpack.Name)), WithName("json-envelope"))
+		assert.Equal(t, "json-envelope", c.Name())
+		var res userV1
+		assert.NoError(t, c.Unmarshal(legacy, &res))
+		assert.Equal(t, int64(2), res.Id)
+	})
+
+	t.Run("compressed", func(t *testing.T) {
+		inner := compress.New(encoding.GetCodec(msgpack.PlainName), compress.WithThreshold(0))
+		encoding.RegisterCodec(inner)
+		c := New(inner)
+
+		data, err := c.Marshal(&userV1{Id: 3})
+		assert.NoError(t, err)
+		var res userV1
+		assert.NoError(t, New(encoding.GetCodec("json")).Unmarshal(data, &res))
+		assert.Equal(t, int64(3), res.Id)
+	})
+}
+
+func TestEnvelopeCodecError(t *testing.T) {
+	assert.Panics(t, func() { New(nil) })
+
+	c := New(encoding.GetCodec("json"))
+	_, err := c.Marshal(func() {})
+	assert.Error(t, err)
+
+	var res userV1
+	unknown := Marshal(&Envelope{Codec: "unknown", Data: []byte("{}")})
+	assert.ErrorIs(t, c.Unmarshal(unknown, &res), ErrUnknownCodec)
+
+	_, err = Unmarshal([]byte("{}"))
+	assert.ErrorIs(t, err, ErrInvalid)
+	for _, body := range [][]byte{
+		{},
+		{10, 'j'},
+		{0},
+		{0, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01},
+		{0, 1},
+	} {
+		data := append(encoding.AppendHeader(nil, encoding.FormatEnvelope), body...)
+		assert.ErrorIs(t, c.Unmarshal(data, &res), ErrInvalid)
+	}
+}
+
+func TestEnvelopeCodecLegacyCollision(t *testing.T) {
+	pb := encoding.GetCodec(proto.Name)
+	c := New(pb)
+	value, err := pb.Marshal(wrapperspb.String("jetcache-go"))
+	assert.NoError(t, err)
+
+	for _, prefix := range [][]byte{
+		// field 24, fixed64: the tag 0xc1 0x01 starts with the magic byte.
+		{0xc1, 0x01, 1, 2, 3, 4, 5, 6, 7, 8},
+		// field 1704, fixed64: the tag and the value start with a full header.
+		{0xc1, 'j', 'c', encoding.FormatEnvelope, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	} {
+		data := append(prefix, value...)
+		assert.Equal(t, prefix[1] == 'j', HasHeader(data))
+		assert.False(t, compress.HasHeader(data))
+
+		var res wrapperspb.StringValue
+		assert.NoError(t, c.Unmarshal(data, &res))
+		assert.Equal(t, "jetcache-go", res.GetValue())
+	}
+}
@@ -0,0 +1,36 @@
+package gob
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/mgtv-tech/jetcache-go/encoding"
+)
+
+// Name is the name registered for the gob codec.
+const Name = "gob"
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// codec is a Codec implementation with gob. Concrete types stored in
+// interface values must be registered with gob.Register.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (codec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (codec) Name() string {
+	return Name
+}
@@ -0,0 +1,49 @@
+package gob
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testMessage struct {
+	Id    int64
+	Name  string
+	Hobby []string
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "gob", codec{}.Name())
+}
+
+func TestGobCodec(t *testing.T) {
+	tests := []testMessage{
+		{
+			Id:   1,
+			Name: "jetcache-go",
+		},
+		{
+			Id:    1,
+			Name:  strings.Repeat("my very large string", 10),
+			Hobby: []string{"study", "eat", "play"},
+		},
+	}
+
+	for _, v := range tests {
+		data, err := codec{}.Marshal(&v)
+		assert.NoError(t, err)
+
+		var res testMessage
+		assert.NoError(t, codec{}.Unmarshal(data, &res))
+		assert.Equal(t, v, res)
+	}
+}
+
+func TestGobCodecError(t *testing.T) {
+	_, err := codec{}.Marshal(func() {})
+	assert.Error(t, err)
+
+	var res testMessage
+	assert.Error(t, codec{}.Unmarshal([]byte("invalid"), &res))
+}
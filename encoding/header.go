@@ -0,0 +1,32 @@
+package encoding
+
+import "bytes"
+
+// Magic starts the header of the payloads wrapped by jetcache-go, such as the
+// compressed, enveloped and encrypted values. 0xc1 is never used by msgpack
+// nor valid in UTF-8 json, and the next two bytes make an accidental match with
+// the payloads of the other codecs unlikely, such as a protobuf message
+// starting with the field 24 fixed64 tag 0xc1 0x01.
+var Magic = [3]byte{0xc1, 'j', 'c'}
+
+// The formats of the headers, following Magic. Every format is registered
+// here, so that no two of them share a byte.
+const (
+	FormatCompress  byte = 0x01 // see package compress.
+	FormatEnvelope  byte = 0x02 // see package envelope.
+	FormatEncrypted byte = 0x03 // see remote.EncryptedRemote.
+	FormatNotFound  byte = 0x04 // the not found placeholder of the cache.
+)
+
+// HeaderLen is the length of a header: Magic followed by the format.
+const HeaderLen = len(Magic) + 1
+
+// AppendHeader appends the header of the format to b.
+func AppendHeader(b []byte, format byte) []byte {
+	return append(append(b, Magic[:]...), format)
+}
+
+// HasHeader reports whether the data starts with the header of the format.
+func HasHeader(data []byte, format byte) bool {
+	return len(data) >= HeaderLen && bytes.Equal(data[:len(Magic)], Magic[:]) && data[len(Magic)] == format
+}
@@ -0,0 +1,23 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader(t *testing.T) {
+	data := AppendHeader([]byte{}, FormatEnvelope)
+	assert.Len(t, data, HeaderLen)
+	assert.True(t, HasHeader(data, FormatEnvelope))
+	assert.False(t, HasHeader(data, FormatCompress))
+	assert.False(t, HasHeader(data[:HeaderLen-1], FormatEnvelope))
+	// a protobuf field 24 fixed64 tag.
+	assert.False(t, HasHeader([]byte{0xc1, 0x01, 'j', 'c', FormatEnvelope}, FormatEnvelope))
+
+	formats := map[byte]bool{}
+	for _, format := range []byte{FormatCompress, FormatEnvelope, FormatEncrypted, FormatNotFound} {
+		assert.False(t, formats[format])
+		formats[format] = true
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"github.com/mgtv-tech/jetcache-go/encoding"
 )
 
-// Name is the name registered for the json codec.
+// Name is the name registered for the msgpack codec, PlainName the one for
+// the msgpack codec without compression nor trailing flag byte, to wrap with
+// the compress codec.
 const (
-	Name = "msgpack"
+	Name      = "msgpack"
+	PlainName = "msgpack-plain"
 
 	compressionThreshold = 64
 	timeLen              = 4
@@ -22,6 +25,7 @@ const (
 
 func init() {
 	encoding.RegisterCodec(codec{})
+	encoding.RegisterCodec(plainCodec{})
 }
 
 // codec is a Codec implementation with json.
@@ -59,6 +63,21 @@ func (codec) Name() string {
 	return Name
 }
 
+// plainCodec is a Codec implementation with msgpack, without compression.
+type plainCodec struct{}
+
+func (plainCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (plainCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func (plainCodec) Name() string {
+	return PlainName
+}
+
 func compress(data []byte) []byte {
 	if len(data) < compressionThreshold {
 		n := len(data) + 1
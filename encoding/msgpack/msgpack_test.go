@@ -52,3 +52,15 @@ func TestMsgpackCodec(t *testing.T) {
 		}
 	}
 }
+
+func TestPlainCodec(t *testing.T) {
+	assert.Equal(t, "msgpack-plain", plainCodec{}.Name())
+
+	v := testMessage{Id: 1, Name: strings.Repeat("my very large string", 10)}
+	data, err := plainCodec{}.Marshal(&v)
+	assert.NoError(t, err)
+
+	var res testMessage
+	assert.NoError(t, plainCodec{}.Unmarshal(data, &res))
+	assert.Equal(t, v, res)
+}
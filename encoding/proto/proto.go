@@ -0,0 +1,70 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/mgtv-tech/jetcache-go/encoding"
+)
+
+// Name is the name registered for the proto codec.
+const Name = "proto"
+
+// ErrNotMessage is returned when the value is not a proto.Message.
+var ErrNotMessage = errors.New("value is not a proto.Message")
+
+var messageType = reflect.TypeOf((*proto.Message)(nil)).Elem()
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// codec is a Codec implementation with protobuf.
+type codec struct{}
+
+func (codec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("proto: marshal %T: %w", v, ErrNotMessage)
+	}
+
+	return proto.Marshal(m)
+}
+
+// Unmarshal parses the data into v, which is a proto.Message or a pointer to
+// a proto.Message such as the **pb.Message passed by T[K, *pb.Message]. A nil
+// message pointed by v is allocated.
+func (codec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		if m, ok = message(v); !ok {
+			return fmt.Errorf("proto: unmarshal %T: %w", v, ErrNotMessage)
+		}
+	}
+
+	return proto.Unmarshal(data, m)
+}
+
+func (codec) Name() string {
+	return Name
+}
+
+func message(v any) (proto.Message, bool) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil, false
+	}
+
+	elem := rv.Elem()
+	if elem.Kind() != reflect.Pointer || !elem.Type().Implements(messageType) {
+		return nil, false
+	}
+	if elem.IsNil() {
+		elem.Set(reflect.New(elem.Type().Elem()))
+	}
+
+	return elem.Interface().(proto.Message), true
+}
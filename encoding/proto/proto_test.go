@@ -0,0 +1,53 @@
+package proto
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "proto", codec{}.Name())
+}
+
+func TestProtoCodec(t *testing.T) {
+	data, err := codec{}.Marshal(wrapperspb.String("jetcache-go"))
+	assert.NoError(t, err)
+
+	t.Run("message", func(t *testing.T) {
+		var res wrapperspb.StringValue
+		assert.NoError(t, codec{}.Unmarshal(data, &res))
+		assert.Equal(t, "jetcache-go", res.GetValue())
+	})
+
+	t.Run("pointer to nil message", func(t *testing.T) {
+		var res *wrapperspb.StringValue
+		assert.NoError(t, codec{}.Unmarshal(data, &res))
+		assert.Equal(t, "jetcache-go", res.GetValue())
+	})
+
+	t.Run("pointer to message", func(t *testing.T) {
+		res := &wrapperspb.StringValue{}
+		assert.NoError(t, codec{}.Unmarshal(data, &res))
+		assert.True(t, proto.Equal(wrapperspb.String("jetcache-go"), res))
+	})
+}
+
+func TestProtoCodecNotMessage(t *testing.T) {
+	type notMessage struct{ Value string }
+
+	_, err := codec{}.Marshal(&notMessage{Value: "jetcache-go"})
+	assert.True(t, errors.Is(err, ErrNotMessage))
+	assert.ErrorContains(t, err, "*proto.notMessage")
+
+	var res notMessage
+	err = codec{}.Unmarshal([]byte{}, &res)
+	assert.True(t, errors.Is(err, ErrNotMessage))
+
+	var s string
+	assert.True(t, errors.Is(codec{}.Unmarshal([]byte{}, &s), ErrNotMessage))
+	assert.True(t, errors.Is(codec{}.Unmarshal([]byte{}, nil), ErrNotMessage))
+}
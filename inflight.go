@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+)
+
+// errAbandoned is the error of a flight finished without a result, such as
+// the ids an MGet found in the local cache. Its waiters read the key again.
+var errAbandoned = errors.New("jetcache: flight abandoned")
+
+type (
+	// flightGroup deduplicates the concurrent reads and loads of a key, like
+	// singleflight.Group, except that an MGet takes part per key: Once, Get and
+	// MGet calls waiting on the same key share one load.
+	flightGroup struct {
+		mu sync.Mutex
+		m  map[string]*flight
+	}
+
+	// flight is an in-flight read or load of a key.
+	flight struct {
+		g      *flightGroup
+		key    string
+		once   sync.Once
+		done   chan struct{}
+		b      []byte
+		cached bool // b was read from the cache rather than loaded.
+		err    error
+	}
+
+	// flights are the flights of an MGet by key.
+	flights map[string]*flight
+)
+
+// do runs fn unless a flight of the key is in progress, in which case it waits
+// for its result instead.
+func (g *flightGroup) do(key string, fn func() ([]byte, bool, error)) ([]byte, bool, error) {
+	for {
+		owned, waiting := g.claim(key)
+		f, ok := waiting[key]
+		if !ok {
+			return owned[key].run(fn)
+		}
+
+		<-f.done
+		if f.err != errAbandoned {
+			return f.b, f.cached, f.err
+		}
+	}
+}
+
+// claim starts the flights of the keys without one in progress, and returns
+// them as owned, along with the flights in progress of the other keys. The
+// owner must finish or abandon its flights.
+func (g *flightGroup) claim(keys ...string) (owned, waiting flights) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.m == nil {
+		g.m = make(map[string]*flight)
+	}
+	owned = make(flights, len(keys))
+	for _, key := range keys {
+		if f, ok := g.m[key]; ok {
+			if waiting == nil {
+				waiting = make(flights)
+			}
+			waiting[key] = f
+			continue
+		}
+		f := &flight{g: g, key: key, done: make(chan struct{})}
+		g.m[key] = f
+		owned[key] = f
+	}
+
+	return
+}
+
+// run finishes the flight with the result of fn. A panicking fn abandons the
+// flight, so that its waiters do not block.
+func (f *flight) run(fn func() ([]byte, bool, error)) ([]byte, bool, error) {
+	defer f.finish(nil, false, errAbandoned)
+
+	b, cached, err := fn()
+	f.finish(b, cached, err)
+
+	return b, cached, err
+}
+
+// finish sets the result of the flight and wakes up its waiters, once.
+func (f *flight) finish(b []byte, cached bool, err error) {
+	f.once.Do(func() {
+		f.g.mu.Lock()
+		if f.g.m[f.key] == f {
+			delete(f.g.m, f.key)
+		}
+		f.g.mu.Unlock()
+
+		f.b, f.cached, f.err = b, cached, err
+		close(f.done)
+	})
+}
+
+// finish finishes the flight of the key, if owned.
+func (fl flights) finish(key string, b []byte, cached bool, err error) {
+	if f, ok := fl[key]; ok {
+		f.finish(b, cached, err)
+	}
+}
+
+// abandon finishes the unfinished flights without a result.
+func (fl flights) abandon() {
+	for _, f := range fl {
+		f.finish(nil, false, errAbandoned)
+	}
+}
@@ -0,0 +1,307 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+// failingMSetRemote fails the MSet calls.
+type failingMSetRemote struct {
+	remote.Remote
+	err error
+}
+
+func (r *failingMSetRemote) MSet(context.Context, map[string]any, time.Duration) error {
+	return r.err
+}
+
+func TestFlightGroup(t *testing.T) {
+	t.Run("share", func(t *testing.T) {
+		var (
+			g     flightGroup
+			calls int32
+			wg    sync.WaitGroup
+		)
+		release := make(chan struct{})
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b, _, err := g.do("key", func() ([]byte, bool, error) {
+					atomic.AddInt32(&calls, 1)
+					<-release
+					return []byte("value"), false, nil
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, "value", string(b))
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+		assert.Empty(t, g.m)
+	})
+
+	t.Run("abandon", func(t *testing.T) {
+		var g flightGroup
+		owned, waiting := g.claim("key1", "key2")
+		assert.Len(t, owned, 2)
+		assert.Empty(t, waiting)
+
+		owned2, waiting2 := g.claim("key2", "key3")
+		assert.Len(t, owned2, 1)
+		assert.Contains(t, waiting2, "key2")
+
+		done := make(chan []byte)
+		go func() {
+			b, _, _ := g.do("key1", func() ([]byte, bool, error) {
+				return []byte("rerun"), false, nil
+			})
+			done <- b
+		}()
+		owned.finish("key2", []byte("value2"), true, nil)
+		owned.abandon()
+		owned2.abandon()
+		assert.Equal(t, "rerun", string(<-done))
+		assert.Equal(t, "value2", string(waiting2["key2"].b))
+		assert.True(t, waiting2["key2"].cached)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		var g flightGroup
+		assert.Panics(t, func() {
+			_, _, _ = g.do("key", func() ([]byte, bool, error) {
+				panic("test panic")
+			})
+		})
+		b, _, err := g.do("key", func() ([]byte, bool, error) {
+			return []byte("value"), false, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "value", string(b))
+	})
+}
+
+func TestMGetShared(t *testing.T) {
+	ctx := context.Background()
+	c := New(WithName("shared"), WithRemote(remote.NewGoRedisV9Adapter(newRdb())), WithLocal(localNew(freeCache)))
+	defer c.Close()
+
+	t.Run("overlapping ids", func(t *testing.T) {
+		cacheT := NewT[int, *object](c)
+		var (
+			mu    sync.Mutex
+			loads = make(map[int]int)
+		)
+		started, release := make(chan struct{}), make(chan struct{})
+		fn := func(_ context.Context, ids []int) (map[int]*object, error) {
+			mu.Lock()
+			ret := make(map[int]*object, len(ids))
+			for _, id := range ids {
+				loads[id]++
+				ret[id] = &object{Str: "str", Num: id}
+			}
+			mu.Unlock()
+			return ret, nil
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			ret := cacheT.MGetResults(ctx, "overlap", []int{1, 2}, func(ctx context.Context, ids []int) (map[int]*object, error) {
+				close(started)
+				<-release
+				return fn(ctx, ids)
+			})
+			assert.Len(t, ret.Values(), 2)
+		}()
+		<-started
+		go func() {
+			defer wg.Done()
+			ret := cacheT.MGetResults(ctx, "overlap", []int{2, 3}, fn)
+			assert.NoError(t, ret.Err)
+			assert.Equal(t, 2, ret.Items[2].Value.Num)
+			assert.Equal(t, StatusLoaded, ret.Items[3].Status)
+		}()
+		go func() {
+			defer wg.Done()
+			v, err := cacheT.Get(ctx, "overlap", 2, func(context.Context, int) (*object, error) {
+				t.Error("the in-flight id is not loaded again")
+				return nil, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, 2, v.Num)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, map[int]int{1: 1, 2: 1, 3: 1}, loads)
+	})
+
+	t.Run("shared load error", func(t *testing.T) {
+		cacheT := NewT[int, *object](c)
+		errLoad := errors.New("load error")
+		started, release := make(chan struct{}), make(chan struct{})
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ret := cacheT.MGetResults(ctx, "sharedErr", []int{1}, func(context.Context, []int) (map[int]*object, error) {
+				close(started)
+				<-release
+				return nil, errLoad
+			})
+			assert.Equal(t, StatusLoadError, ret.Items[1].Status)
+		}()
+		<-started
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+		ret := cacheT.MGetResults(ctx, "sharedErr", []int{1}, func(context.Context, []int) (map[int]*object, error) {
+			t.Error("the in-flight id is not loaded again")
+			return nil, nil
+		})
+		wg.Wait()
+		assert.Equal(t, StatusLoadError, ret.Items[1].Status)
+		assert.ErrorIs(t, ret.Items[1].Err, errLoad)
+	})
+
+	t.Run("coalesce", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithCoalesceWindow[*object](100*time.Millisecond))
+		var (
+			mu    sync.Mutex
+			calls [][]int
+		)
+		fn := func(_ context.Context, ids []int) (map[int]*object, error) {
+			sorted := append([]int(nil), ids...)
+			sort.Ints(sorted)
+			mu.Lock()
+			calls = append(calls, sorted)
+			mu.Unlock()
+			ret := make(map[int]*object, len(ids))
+			for _, id := range ids {
+				ret[id] = &object{Str: "str", Num: id}
+			}
+			return ret, nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 3; i++ {
+			id := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ret := cacheT.MGetResults(ctx, "coalesce", []int{id}, fn)
+				assert.NoError(t, ret.Err)
+				assert.Equal(t, StatusLoaded, ret.Items[id].Status)
+				assert.Equal(t, id, ret.Items[id].Value.Num)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, [][]int{{1, 2, 3}}, calls)
+	})
+
+	t.Run("coalesce by load function", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithCoalesceWindow[*object](50*time.Millisecond))
+		var calls int32
+		fnA := func(_ context.Context, ids []int) (map[int]*object, error) {
+			atomic.AddInt32(&calls, 1)
+			return map[int]*object{ids[0]: {Str: "a", Num: ids[0]}}, nil
+		}
+		fnB := func(_ context.Context, ids []int) (map[int]*object, error) {
+			atomic.AddInt32(&calls, 1)
+			return map[int]*object{ids[0]: {Str: "b", Num: ids[0]}}, nil
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ret := cacheT.MGetResults(ctx, "coalesceFn", []int{1}, fnA)
+			assert.Equal(t, "a", ret.Items[1].Value.Str)
+		}()
+		go func() {
+			defer wg.Done()
+			ret := cacheT.MGetResults(ctx, "coalesceFn", []int{2}, fnB)
+			assert.Equal(t, "b", ret.Items[2].Value.Str)
+		}()
+		wg.Wait()
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("coalesce first caller canceled", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithCoalesceWindow[*object](50*time.Millisecond))
+		fn := func(ctx context.Context, ids []int) (map[int]*object, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			ret := make(map[int]*object, len(ids))
+			for _, id := range ids {
+				ret[id] = &object{Str: "str", Num: id}
+			}
+			return ret, nil
+		}
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		firstCh := make(chan BatchResult[int, *object], 1)
+		go func() {
+			firstCh <- cacheT.MGetResults(cancelCtx, "coalesceCanceled", []int{1}, fn)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		retCh := make(chan BatchResult[int, *object], 1)
+		go func() {
+			retCh <- cacheT.MGetResults(ctx, "coalesceCanceled", []int{2}, fn)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		ret := <-retCh
+		assert.NoError(t, ret.Err)
+		assert.Equal(t, StatusLoaded, ret.Items[2].Status)
+		first := <-firstCh
+		assert.Equal(t, StatusLoadError, first.Items[1].Status)
+		assert.ErrorIs(t, first.Items[1].Err, context.Canceled)
+	})
+
+	t.Run("coalesce error", func(t *testing.T) {
+		errSet := errors.New("mset error")
+		failC := New(WithName("coalesceErr"), WithRemote(&failingMSetRemote{Remote: remote.NewGoRedisV9Adapter(newRdb()), err: errSet}))
+		defer failC.Close()
+		cacheT := NewT[int, *object](failC, WithCoalesceWindow[*object](50*time.Millisecond))
+		fn := func(_ context.Context, ids []int) (map[int]*object, error) {
+			ret := make(map[int]*object, len(ids))
+			for _, id := range ids {
+				ret[id] = &object{Str: "str", Num: id}
+			}
+			return ret, nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 1; i <= 3; i++ {
+			id := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// every caller sees the error of writing the batch.
+				ret := cacheT.MGetResults(ctx, "coalesceErr", []int{id}, fn)
+				assert.ErrorContains(t, ret.Err, errSet.Error())
+				assert.Equal(t, StatusLoaded, ret.Items[id].Status)
+			}()
+		}
+		wg.Wait()
+	})
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/mgtv-tech/jetcache-go/logger"
@@ -15,25 +16,34 @@ type (
 	DoFunc func(ctx context.Context) (any, error)
 
 	item struct {
-		ctx       context.Context
-		key       string
-		value     any           // value gets the value for the given key and fills into value.
-		ttl       time.Duration // ttl is the remote cache expiration time. Default ttl is 1 hour.
-		do        DoFunc        // do is DoFunc
-		setXX     bool          // setXX only sets the key if it already exists.
-		setNX     bool          // setNX only sets the key if it does not already exist.
-		skipLocal bool          // skipLocal skips local cache as if it is not set.
-		refresh   bool          // refresh open cache async refresh.
+		ctx             context.Context
+		key             string
+		value           any           // value gets the value for the given key and fills into value.
+		ttl             time.Duration // ttl is the remote cache expiration time. Default ttl is 1 hour.
+		do              DoFunc        // do is DoFunc
+		setXX           bool          // setXX only sets the key if it already exists.
+		setNX           bool          // setNX only sets the key if it does not already exist.
+		skipLocal       bool          // skipLocal skips local cache as if it is not set.
+		refresh         bool          // refresh open cache async refresh.
+		refreshDuration time.Duration // refreshDuration is the refresh interval of the key. Default is the cache refreshDuration.
+		notFoundTTL     time.Duration // notFoundTTL is the remote expiration time of the not found placeholder. Default is the cache notFoundExpiry.
 	}
 
 	refreshTask struct {
-		key            string
-		ttl            time.Duration
-		do             DoFunc
-		setXX          bool
-		setNX          bool
-		skipLocal      bool
-		lastAccessTime time.Time
+		key             string
+		ttl             time.Duration
+		do              DoFunc
+		setXX           bool
+		setNX           bool
+		skipLocal       bool
+		refreshDuration time.Duration
+		notFoundTTL     time.Duration
+		mu              sync.Mutex // guards lastAccessTime, lastRefreshTime and lastErr.
+		lastAccessTime  time.Time
+		lastRefreshTime time.Time
+		lastErr         error
+		nextRefreshTime time.Time // guarded by jetCache.refreshMu.
+		index           int       // index in the refresh queue, -1 if not queued.
 	}
 )
 
@@ -88,6 +98,22 @@ func Refresh(refresh bool) ItemOption {
 	}
 }
 
+// RefreshDuration sets the refresh interval of the key, overriding the cache
+// refreshDuration. It takes effect together with Refresh(true).
+func RefreshDuration(refreshDuration time.Duration) ItemOption {
+	return func(o *item) {
+		o.refreshDuration = refreshDuration
+	}
+}
+
+// NotFoundTTL sets the remote expiration time of the placeholder cached when
+// the DoFunc returns errNotFound, overriding the cache notFoundExpiry.
+func NotFoundTTL(notFoundTTL time.Duration) ItemOption {
+	return func(o *item) {
+		o.notFoundTTL = notFoundTTL
+	}
+}
+
 func (item *item) Context() context.Context {
 	if item.ctx == nil {
 		return context.Background()
@@ -121,12 +147,46 @@ func (item *item) getTtl(defaultTTL time.Duration) time.Duration {
 	return defaultTTL
 }
 
-func (item *item) toRefreshTask() *refreshTask {
+func (item *item) getRefreshDuration(defaultDuration time.Duration) time.Duration {
+	if item.refreshDuration <= 0 {
+		return defaultDuration
+	}
+
+	if item.refreshDuration < minEffectRefreshDuration {
+		return minEffectRefreshDuration
+	}
+
+	return item.refreshDuration
+}
+
+func (item *item) toRefreshTask(refreshDuration time.Duration) *refreshTask {
 	return &refreshTask{
-		key:            item.key,
-		ttl:            item.ttl,
-		do:             item.do,
-		skipLocal:      item.skipLocal,
-		lastAccessTime: time.Now(),
+		key:             item.key,
+		ttl:             item.ttl,
+		do:              item.do,
+		skipLocal:       item.skipLocal,
+		refreshDuration: refreshDuration,
+		notFoundTTL:     item.notFoundTTL,
+		lastAccessTime:  time.Now(),
+		index:           -1,
 	}
 }
+
+func (task *refreshTask) access(now time.Time) {
+	task.mu.Lock()
+	task.lastAccessTime = now
+	task.mu.Unlock()
+}
+
+func (task *refreshTask) accessedSince(t time.Time) bool {
+	task.mu.Lock()
+	defer task.mu.Unlock()
+	return !task.lastAccessTime.Before(t)
+}
+
+func (task *refreshTask) done(now time.Time, err error) {
+	task.mu.Lock()
+	task.lastRefreshTime = now
+	task.lastErr = err
+	task.mu.Unlock()
+}
@@ -28,7 +28,7 @@ func TestItemOptions(t *testing.T) {
 	t.Run("with item options", func(t *testing.T) {
 		o := newItemOptions(context.TODO(), "key", Value("getValue"),
 			TTL(time.Minute), SetXX(true), SetNX(true), SkipLocal(true),
-			Refresh(true), Do(func(context.Context) (any, error) {
+			Refresh(true), RefreshDuration(time.Minute), Do(func(context.Context) (any, error) {
 				return "any", nil
 			}))
 		assert.Equal(t, "getValue", o.value)
@@ -38,6 +38,7 @@ func TestItemOptions(t *testing.T) {
 		assert.True(t, o.setNX)
 		assert.True(t, o.skipLocal)
 		assert.True(t, o.refresh)
+		assert.Equal(t, time.Minute, o.getRefreshDuration(time.Second))
 	})
 }
 
@@ -65,3 +66,28 @@ func TestItemTTL(t *testing.T) {
 		assert.Equal(t, v.expect, item.getTtl(defaultRemoteExpiry))
 	}
 }
+
+func TestItemRefreshDuration(t *testing.T) {
+	tests := []struct {
+		input  time.Duration
+		expect time.Duration
+	}{
+		{
+			input:  0,
+			expect: time.Hour,
+		},
+		{
+			input:  time.Millisecond,
+			expect: minEffectRefreshDuration,
+		},
+		{
+			input:  time.Minute,
+			expect: time.Minute,
+		},
+	}
+
+	for _, v := range tests {
+		item := &item{refreshDuration: v.input}
+		assert.Equal(t, v.expect, item.getRefreshDuration(time.Hour))
+	}
+}
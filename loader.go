@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+const (
+	defaultLoaderWait     = time.Millisecond
+	defaultLoaderMaxBatch = 100
+)
+
+type (
+	// LoaderOption defines the method to customize a Loader.
+	LoaderOption func(o *loaderOptions)
+
+	loaderOptions struct {
+		wait     time.Duration
+		maxBatch int
+	}
+
+	// Loader batches the Load calls of the ids of a key, DataLoader-style:
+	// the ids requested within the wait, or up to the max batch size, are
+	// resolved by one T.MGetResults with the batch load function.
+	Loader[K constraints.Ordered, V any] struct {
+		t           *T[K, V]
+		key         string
+		fn          func(context.Context, []K) (map[K]V, error)
+		wait        time.Duration
+		maxBatch    int
+		errNotFound error // returned for the ids cached as not found, see Load.
+		mu          sync.Mutex
+		batch       *loaderBatch[K, V] // the batch collecting ids, guarded by mu.
+	}
+
+	// loaderBatch is a batch of ids resolved by one T.MGetResults.
+	loaderBatch[K constraints.Ordered, V any] struct {
+		ctx   context.Context // the context of the first Load, without its cancellation.
+		ids   []K
+		seen  map[K]struct{}
+		timer *time.Timer
+		once  sync.Once
+		done  chan struct{}
+		ret   BatchResult[K, V]
+	}
+
+	// loaderMemo memoizes the batches of the ids loaded in a request, see
+	// NewLoaderContext.
+	loaderMemo struct {
+		mu sync.Mutex
+		m  map[loaderMemoKey]any
+	}
+
+	loaderMemoKey struct {
+		loader any
+		id     any
+	}
+
+	loaderMemoCtxKey struct{}
+)
+
+// WithLoaderWait sets how long a Loader collects ids before loading them,
+// defaulting to 1ms.
+func WithLoaderWait(wait time.Duration) LoaderOption {
+	return func(o *loaderOptions) {
+		o.wait = wait
+	}
+}
+
+// WithLoaderMaxBatch sets the maximum number of ids loaded by a Loader at
+// once, defaulting to 100. A full batch is loaded without waiting.
+func WithLoaderMaxBatch(maxBatch int) LoaderOption {
+	return func(o *loaderOptions) {
+		o.maxBatch = maxBatch
+	}
+}
+
+// NewLoader new a Loader of the ids of the key, loading the missed ids by fn.
+// A Loader is safe for concurrent use, and is usually created once per T and
+// key, next to the T.
+func NewLoader[K constraints.Ordered, V any](t *T[K, V], key string, fn func(context.Context, []K) (map[K]V, error), opts ...LoaderOption) *Loader[K, V] {
+	o := loaderOptions{
+		wait:     defaultLoaderWait,
+		maxBatch: defaultLoaderMaxBatch,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxBatch <= 0 {
+		o.maxBatch = defaultLoaderMaxBatch
+	}
+
+	errNotFound := t.Cache.(*jetCache).errNotFound
+	if errNotFound == nil {
+		errNotFound = ErrCacheMiss
+	}
+
+	return &Loader[K, V]{
+		t:           t,
+		key:         key,
+		fn:          fn,
+		wait:        o.wait,
+		maxBatch:    o.maxBatch,
+		errNotFound: errNotFound,
+	}
+}
+
+// NewLoaderContext returns a context memoizing the results of the Loaders for
+// its scope, such as a request: a Load of an id already loaded with the
+// context returns the same result without asking the cache again.
+func NewLoaderContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loaderMemoCtxKey{}, &loaderMemo{m: make(map[loaderMemoKey]any)})
+}
+
+// Load returns the value of the id, loaded with the other ids requested
+// meanwhile. It returns the not found error of the cache if the id is cached
+// as not found, or ErrCacheMiss if it is neither cached nor loaded.
+func (l *Loader[K, V]) Load(ctx context.Context, id K) (v V, err error) {
+	item, err := l.enqueue(ctx, id).wait(ctx, id)
+	switch {
+	case err != nil:
+		return v, err
+	case item.OK():
+		return item.Value, nil
+	case item.Status == StatusNotFound:
+		return v, l.errNotFound
+	case item.Status == StatusMiss:
+		return v, ErrCacheMiss
+	default:
+		return v, item.Err
+	}
+}
+
+// LoadMany returns the values of the ids, as T.MGetWithErr does.
+func (l *Loader[K, V]) LoadMany(ctx context.Context, ids []K) (map[K]V, error) {
+	batches := make([]*loaderBatch[K, V], len(ids))
+	for i, id := range ids {
+		batches[i] = l.enqueue(ctx, id)
+	}
+
+	var errs error
+	result := make(map[K]V, len(ids))
+	for i, id := range ids {
+		item, err := batches[i].wait(ctx, id)
+		if err != nil {
+			return result, err
+		}
+		if item.hasValue() {
+			result[id] = item.Value
+		}
+		if item.Err != nil {
+			errs = errors.Join(errs, fmt.Errorf("Loader#LoadMany(%v) error(%v)", id, item.Err))
+		}
+	}
+
+	return result, errs
+}
+
+// enqueue adds the id to the batch collecting ids, unless memoized.
+func (l *Loader[K, V]) enqueue(ctx context.Context, id K) *loaderBatch[K, V] {
+	memo, _ := ctx.Value(loaderMemoCtxKey{}).(*loaderMemo)
+	if memo != nil {
+		memo.mu.Lock()
+		defer memo.mu.Unlock()
+		if b, ok := memo.m[loaderMemoKey{loader: l, id: id}]; ok {
+			return b.(*loaderBatch[K, V])
+		}
+	}
+
+	l.mu.Lock()
+	b := l.batch
+	if b == nil {
+		// the batch outlives the Load canceling its context, the others still wait for it.
+		b = &loaderBatch[K, V]{ctx: context.WithoutCancel(ctx), seen: make(map[K]struct{}), done: make(chan struct{})}
+		b.timer = time.AfterFunc(l.wait, func() {
+			l.dispatch(b)
+		})
+		l.batch = b
+	}
+	if _, ok := b.seen[id]; !ok {
+		b.seen[id] = struct{}{}
+		b.ids = append(b.ids, id)
+	}
+	full := len(b.ids) >= l.maxBatch
+	if full {
+		l.batch = nil
+	}
+	l.mu.Unlock()
+
+	if full {
+		b.timer.Stop()
+		go l.dispatch(b)
+	}
+	if memo != nil {
+		memo.m[loaderMemoKey{loader: l, id: id}] = b
+	}
+
+	return b
+}
+
+// dispatch loads the ids of the batch, once. A panic fails all the ids of
+// the batch instead of crashing the process, as dispatch runs in its own
+// goroutine.
+func (l *Loader[K, V]) dispatch(b *loaderBatch[K, V]) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+
+		defer close(b.done)
+		defer func() {
+			if r := recover(); r != nil {
+				err := fmt.Errorf("panic(%v)", r)
+				b.ret = newBatchResult[K, V](len(b.ids))
+				b.ret.failLoad(b.ids, err)
+			}
+		}()
+		b.ret = l.t.MGetResults(b.ctx, l.key, b.ids, l.fn)
+	})
+}
+
+// wait waits for the batch and returns the result of the id, or the error of
+// the context if done first.
+func (b *loaderBatch[K, V]) wait(ctx context.Context, id K) (ItemResult[V], error) {
+	select {
+	case <-b.done:
+		return b.ret.Items[id], nil
+	case <-ctx.Done():
+		return ItemResult[V]{}, ctx.Err()
+	}
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+func TestLoader(t *testing.T) {
+	c := New(WithName("loader"), WithRemote(remote.NewGoRedisV9Adapter(newRdb())), WithLocal(localNew(freeCache)),
+		WithErrNotFound(errTestNotFound))
+	defer c.Close()
+	cacheT := NewT[int, *object](c)
+
+	var (
+		mu    sync.Mutex
+		calls [][]int
+	)
+	fn := func(_ context.Context, ids []int) (map[int]*object, error) {
+		sorted := append([]int(nil), ids...)
+		sort.Ints(sorted)
+		mu.Lock()
+		calls = append(calls, sorted)
+		mu.Unlock()
+		ret := make(map[int]*object, len(ids))
+		for _, id := range ids {
+			if id != 9 {
+				ret[id] = &object{Str: "str", Num: id}
+			}
+		}
+		return ret, nil
+	}
+	reset := func() {
+		mu.Lock()
+		calls = nil
+		mu.Unlock()
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		reset()
+		loader := NewLoader(cacheT, "batch", fn, WithLoaderWait(20*time.Millisecond))
+		var wg sync.WaitGroup
+		for i := 1; i <= 5; i++ {
+			id := i
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := loader.Load(context.Background(), id%3+1)
+				assert.NoError(t, err)
+				assert.Equal(t, id%3+1, v.Num)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, [][]int{{1, 2, 3}}, calls)
+	})
+
+	t.Run("max batch", func(t *testing.T) {
+		reset()
+		loader := NewLoader(cacheT, "maxBatch", fn, WithLoaderWait(time.Hour), WithLoaderMaxBatch(2))
+		ret, err := loader.LoadMany(context.Background(), []int{1, 2, 3, 4})
+		assert.NoError(t, err)
+		assert.Len(t, ret, 4)
+		assert.ElementsMatch(t, [][]int{{1, 2}, {3, 4}}, calls)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		loader := NewLoader(cacheT, "notFound", fn)
+		_, err := loader.Load(context.Background(), 9)
+		assert.ErrorIs(t, err, errTestNotFound)
+
+		ret, err := loader.LoadMany(context.Background(), []int{8, 9})
+		assert.NoError(t, err)
+		assert.Len(t, ret, 1)
+
+		_, err = NewLoader(cacheT, "miss", nil).Load(context.Background(), 1)
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+
+	t.Run("memo", func(t *testing.T) {
+		reset()
+		loader := NewLoader(cacheT, "memo", fn)
+		ctx := NewLoaderContext(context.Background())
+		v1, err := loader.Load(ctx, 1)
+		assert.NoError(t, err)
+		assert.NoError(t, c.Delete(ctx, "memo:1"))
+
+		v2, err := loader.Load(ctx, 1)
+		assert.NoError(t, err)
+		assert.Same(t, v1, v2)
+		assert.Len(t, calls, 1)
+
+		_, err = loader.Load(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Len(t, calls, 2)
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		loader := NewLoader(cacheT, "done", fn, WithLoaderWait(time.Hour))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := loader.Load(ctx, 1)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("first caller canceled", func(t *testing.T) {
+		loader := NewLoader(cacheT, "canceled", func(ctx context.Context, ids []int) (map[int]*object, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return fn(ctx, ids)
+		}, WithLoaderWait(20*time.Millisecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := loader.Load(ctx, 1)
+			errCh <- err
+		}()
+		time.Sleep(5 * time.Millisecond)
+		cancel()
+
+		v, err := loader.Load(context.Background(), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v.Num)
+		assert.ErrorIs(t, <-errCh, context.Canceled)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		loader := NewLoader(cacheT, "panic", func(context.Context, []int) (map[int]*object, error) {
+			panic("load panic")
+		}, WithLoaderWait(5*time.Millisecond))
+
+		_, err := loader.Load(context.Background(), 1)
+		assert.ErrorContains(t, err, "load panic")
+		ret, err := loader.LoadMany(context.Background(), []int{1, 2})
+		assert.ErrorContains(t, err, "load panic")
+		assert.Empty(t, ret)
+
+		// the flights of the failed batch are released.
+		v, err := NewLoader(cacheT, "panic", fn).Load(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v.Num)
+	})
+}
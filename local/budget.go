@@ -0,0 +1,177 @@
+package local
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go/logger"
+)
+
+var (
+	// ErrBudgetExceeded is returned when a reservation exceeds the hard limit of a Budget.
+	ErrBudgetExceeded = errors.New("local: memory budget exceeded")
+	// ErrInvalidSize is returned by the Budget constructors for a size out of
+	// the range of the cache, instead of reserving the default size.
+	ErrInvalidSize = errors.New("local: invalid cache size")
+)
+
+type (
+	// Budget is a global memory budget split across local caches. Each cache reserves
+	// its capacity from the budget: reservations beyond the soft limit are granted
+	// with a warning, reservations beyond the hard limit fail with ErrBudgetExceeded.
+	// The reservation of a discarded cache is given back by Release.
+	Budget struct {
+		mu           sync.Mutex
+		soft         Size
+		hard         Size
+		reserved     Size
+		reservations []reservation
+	}
+
+	// reservation is the size reserved for a cache.
+	reservation struct {
+		cache MetricsReporter
+		size  Size
+	}
+
+	// BudgetUsage is a snapshot of the usage of a Budget.
+	BudgetUsage struct {
+		Soft     Size
+		Hard     Size
+		Reserved Size    // Sum of the reservations.
+		Local    Metrics // Sum of the metrics of the reserving caches.
+	}
+)
+
+// NewBudget creates a new Budget. The soft limit defaults to the hard limit.
+func NewBudget(soft, hard Size) *Budget {
+	if soft <= 0 || soft > hard {
+		soft = hard
+	}
+
+	return &Budget{
+		soft: soft,
+		hard: hard,
+	}
+}
+
+// Reserve reserves the size from the budget for the cache. The cache, if
+// not nil, is summed up in Usage, and its reservation given back by Release.
+func (b *Budget) Reserve(size Size, cache MetricsReporter) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	reserved := b.reserved + size
+	if reserved > b.hard {
+		return ErrBudgetExceeded
+	}
+	if reserved > b.soft {
+		logger.Warn("budget#Reserve reserved(%d) exceeds the soft limit(%d)", reserved, b.soft)
+	}
+
+	b.reserved = reserved
+	if cache != nil {
+		b.reservations = append(b.reservations, reservation{cache: cache, size: size})
+	}
+
+	return nil
+}
+
+// Release gives back the reservations of the cache, created by the Budget or
+// passed to Reserve, which is no longer summed up in Usage. The cache must no
+// longer be used.
+func (b *Budget) Release(cache MetricsReporter) {
+	if c, ok := cache.(*FreeCache); ok {
+		cache = c.Shared()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	kept := b.reservations[:0]
+	for _, r := range b.reservations {
+		if r.cache == cache {
+			b.reserved -= r.size
+			continue
+		}
+		kept = append(kept, r)
+	}
+	clear(b.reservations[len(kept):])
+	b.reservations = kept
+}
+
+// NewFreeCache reserves the size and creates a FreeCache of it, see NewFreeCache.
+func (b *Budget) NewFreeCache(size Size, ttl time.Duration, innerKeyPrefix ...string) (*FreeCache, error) {
+	shared, err := b.NewSharedFreeCache(size)
+	if err != nil {
+		return nil, err
+	}
+
+	return shared.NewFreeCache(ttl, innerKeyPrefix...), nil
+}
+
+// NewSharedFreeCache reserves the size and creates a SharedFreeCache of it, see
+// NewSharedFreeCache. It fails with ErrInvalidSize for a size out of range.
+func (b *Budget) NewSharedFreeCache(size Size) (*SharedFreeCache, error) {
+	if !validFreeCacheSize(size) {
+		return nil, fmt.Errorf("%w: freecache size(%d) out of [512KB, 8GB]", ErrInvalidSize, size)
+	}
+	if err := b.Reserve(size, nil); err != nil {
+		return nil, err
+	}
+
+	shared := NewSharedFreeCache(size)
+	b.track(shared, size)
+
+	return shared, nil
+}
+
+// NewTinyLFU reserves the size and creates a TinyLFU of it with WithByteCost,
+// see NewTinyLFU. It fails with ErrInvalidSize for a size not positive.
+func (b *Budget) NewTinyLFU(size Size, ttl time.Duration, opts ...TinyLFUOption) (*TinyLFU, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: tinylfu size(%d) not positive", ErrInvalidSize, size)
+	}
+	if err := b.Reserve(size, nil); err != nil {
+		return nil, err
+	}
+
+	c := NewTinyLFU(int(size), ttl, append(opts[:len(opts):len(opts)], WithByteCost(true))...)
+	b.track(c, size)
+
+	return c, nil
+}
+
+// track records the size reserved before the cache was created.
+func (b *Budget) track(cache MetricsReporter, size Size) {
+	b.mu.Lock()
+	b.reservations = append(b.reservations, reservation{cache: cache, size: size})
+	b.mu.Unlock()
+}
+
+// Usage returns the current usage of the budget.
+func (b *Budget) Usage() BudgetUsage {
+	b.mu.Lock()
+	usage := BudgetUsage{
+		Soft:     b.soft,
+		Hard:     b.hard,
+		Reserved: b.reserved,
+	}
+	reservations := append([]reservation(nil), b.reservations...)
+	b.mu.Unlock()
+
+	for _, r := range reservations {
+		m := r.cache.Metrics()
+		usage.Local.Entries += m.Entries
+		usage.Local.Bytes += m.Bytes
+		usage.Local.Capacity += m.Capacity
+		usage.Local.Evictions += m.Evictions
+		usage.Local.Expirations += m.Expirations
+		usage.Local.Overwrites += m.Overwrites
+		usage.Local.Rejections += m.Rejections
+	}
+
+	return usage
+}
@@ -0,0 +1,83 @@
+package local
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	t.Run("soft limit defaults to hard limit", func(t *testing.T) {
+		b := NewBudget(0, 10*MB)
+		assert.Equal(t, 10*MB, b.Usage().Soft)
+		b = NewBudget(20*MB, 10*MB)
+		assert.Equal(t, 10*MB, b.Usage().Soft)
+	})
+
+	t.Run("reserve", func(t *testing.T) {
+		b := NewBudget(2*MB, 3*MB)
+		c1, err := b.NewFreeCache(1*MB, time.Minute)
+		assert.NoError(t, err)
+		_, err = b.NewSharedFreeCache(2 * MB)
+		assert.NoError(t, err) // beyond the soft limit
+		_, err = b.NewFreeCache(1*MB, time.Minute)
+		assert.ErrorIs(t, err, ErrBudgetExceeded)
+		assert.ErrorIs(t, b.Reserve(1, nil), ErrBudgetExceeded)
+
+		c1.Set("key", []byte("value"))
+		usage := b.Usage()
+		assert.Equal(t, 3*MB, usage.Reserved)
+		assert.Equal(t, int64(1), usage.Local.Entries)
+		assert.Equal(t, int64(3*MB), usage.Local.Capacity)
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		b := NewBudget(0, 300*MB)
+		for _, size := range []Size{0, 200 * KB, 9 * GB} {
+			_, err := b.NewFreeCache(size, time.Minute)
+			assert.ErrorIs(t, err, ErrInvalidSize)
+		}
+		_, err := b.NewTinyLFU(0, time.Minute)
+		assert.ErrorIs(t, err, ErrInvalidSize)
+		assert.Zero(t, b.Usage().Reserved)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		b := NewBudget(0, 2*MB)
+		c1, err := b.NewFreeCache(1*MB, time.Minute)
+		assert.NoError(t, err)
+		c2, err := b.NewTinyLFU(1*MB, time.Minute)
+		assert.NoError(t, err)
+		_, err = b.NewTinyLFU(1*MB, time.Minute)
+		assert.ErrorIs(t, err, ErrBudgetExceeded)
+
+		b.Release(c1)
+		assert.Equal(t, 1*MB, b.Usage().Reserved)
+		assert.Equal(t, int64(1*MB), b.Usage().Local.Capacity)
+		c3, err := b.NewTinyLFU(1*MB, time.Minute)
+		assert.NoError(t, err)
+
+		b.Release(c2)
+		b.Release(c2)
+		b.Release(c3)
+		assert.Zero(t, b.Usage().Reserved)
+		assert.Zero(t, b.Usage().Local.Capacity)
+	})
+
+	t.Run("reserve any cache", func(t *testing.T) {
+		b := NewBudget(0, 2000)
+		c1, err := b.NewTinyLFU(1000, time.Minute)
+		assert.NoError(t, err)
+		c2 := NewTinyLFU(1000, time.Minute, WithByteCost(true))
+		assert.NoError(t, b.Reserve(1000, c2))
+		_, err = b.NewTinyLFU(1, time.Minute)
+		assert.ErrorIs(t, err, ErrBudgetExceeded)
+
+		c1.Set("key", []byte("value"))
+		c2.Set("key", []byte("value"))
+		usage := b.Usage()
+		assert.Equal(t, int64(2000), usage.Local.Capacity)
+		assert.Equal(t, 2*(c1.Metrics().Bytes), usage.Local.Bytes)
+	})
+}
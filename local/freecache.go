@@ -1,9 +1,10 @@
 package local
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"sync"
+	"strings"
 	"time"
 
 	"github.com/coocood/freecache"
@@ -12,27 +13,68 @@ import (
 	"github.com/mgtv-tech/jetcache-go/util"
 )
 
-var _ Local = (*FreeCache)(nil)
-
 var (
-	innerCache *freecache.Cache
-	once       sync.Once
+	_ Local           = (*FreeCache)(nil)
+	_ MetricsReporter = (*FreeCache)(nil)
+	_ Clearer         = (*FreeCache)(nil)
+	_ Lener           = (*FreeCache)(nil)
+	_ Ranger          = (*FreeCache)(nil)
+	_ PrefixDeleter   = (*FreeCache)(nil)
+	_ MetricsReporter = (*SharedFreeCache)(nil)
 )
 
 type (
 	FreeCache struct {
+		inner          *SharedFreeCache
 		safeRand       *util.SafeRand
 		ttl            time.Duration
 		offset         time.Duration
 		innerKeyPrefix string
 	}
+
+	// SharedFreeCache is a freecache allocation that several FreeCache instances
+	// can explicitly share, separated by their innerKeyPrefix.
+	SharedFreeCache struct {
+		cache *freecache.Cache
+		size  Size
+	}
+
 	// Option defines the method to customize an Options.
 	Option func(o *FreeCache)
 )
 
-// NewFreeCache Create a new cache instance, but the internal cache instances are shared,
-// and they will only be initialized once.
+// NewSharedFreeCache allocates a freecache of the size to be shared by the
+// FreeCache instances created by its NewFreeCache method.
+func NewSharedFreeCache(size Size) *SharedFreeCache {
+	size = freeCacheSize(size)
+	return &SharedFreeCache{
+		cache: freecache.NewCache(int(size)),
+		size:  size,
+	}
+}
+
+// freeCacheSize replaces the size out of range by the default.
+func freeCacheSize(size Size) Size {
+	if !validFreeCacheSize(size) {
+		return 256 * MB
+	}
+
+	return size
+}
+
+func validFreeCacheSize(size Size) bool {
+	return size >= 512*KB && size <= 8*GB
+}
+
+// NewFreeCache creates a new FreeCache instance backed by its own freecache of the size.
 func NewFreeCache(size Size, ttl time.Duration, innerKeyPrefix ...string) *FreeCache {
+	return NewSharedFreeCache(size).NewFreeCache(ttl, innerKeyPrefix...)
+}
+
+// NewFreeCache creates a new FreeCache instance backed by the shared freecache.
+// Instances sharing it share its capacity and eviction counters, so they should
+// use distinct innerKeyPrefix.
+func (s *SharedFreeCache) NewFreeCache(ttl time.Duration, innerKeyPrefix ...string) *FreeCache {
 	prefix := ""
 	if len(innerKeyPrefix) > 0 {
 		prefix = innerKeyPrefix[0]
@@ -49,14 +91,8 @@ func NewFreeCache(size Size, ttl time.Duration, innerKeyPrefix ...string) *FreeC
 		offset = maxOffset
 	}
 
-	once.Do(func() {
-		if size < 512*KB || size > 8*GB {
-			size = 256 * MB
-		}
-		innerCache = freecache.NewCache(int(size))
-	})
-
 	return &FreeCache{
+		inner:          s,
 		innerKeyPrefix: prefix,
 		safeRand:       util.NewSafeRand(),
 		ttl:            ttl,
@@ -64,6 +100,35 @@ func NewFreeCache(size Size, ttl time.Duration, innerKeyPrefix ...string) *FreeC
 	}
 }
 
+// Capacity returns the memory size of the shared freecache.
+func (s *SharedFreeCache) Capacity() Size {
+	return s.size
+}
+
+// EntryCount returns the number of entries in the shared freecache.
+func (s *SharedFreeCache) EntryCount() int64 {
+	return s.cache.EntryCount()
+}
+
+// EvictionCount returns the number of entries evicted from the shared freecache
+// to make room for new ones, expired entries excluded.
+func (s *SharedFreeCache) EvictionCount() int64 {
+	return s.cache.EvacuateCount()
+}
+
+// Metrics returns the metrics of the shared freecache. FreeCache preallocates
+// its memory, so Bytes equals Capacity.
+func (s *SharedFreeCache) Metrics() Metrics {
+	return Metrics{
+		Entries:     s.cache.EntryCount(),
+		Bytes:       int64(s.size),
+		Capacity:    int64(s.size),
+		Evictions:   s.cache.EvacuateCount(),
+		Expirations: s.cache.ExpiredCount(),
+		Overwrites:  s.cache.OverwriteCount(),
+	}
+}
+
 func (c *FreeCache) UseRandomizedTTL(offset time.Duration) {
 	c.offset = offset
 }
@@ -74,13 +139,13 @@ func (c *FreeCache) Set(key string, b []byte) {
 		ttl += time.Duration(c.safeRand.Int63n(int64(c.offset)))
 	}
 
-	if err := innerCache.Set(util.Bytes(c.Key(key)), b, int(ttl.Seconds())); err != nil {
+	if err := c.inner.cache.Set(util.Bytes(c.Key(key)), b, int(ttl.Seconds())); err != nil {
 		logger.Error("freeCache set(%s) error(%v)", key, err)
 	}
 }
 
 func (c *FreeCache) Get(key string) ([]byte, bool) {
-	b, err := innerCache.Get(util.Bytes(c.Key(key)))
+	b, err := c.inner.cache.Get(util.Bytes(c.Key(key)))
 	if err != nil {
 		if errors.Is(err, freecache.ErrNotFound) {
 			return nil, false
@@ -93,7 +158,7 @@ func (c *FreeCache) Get(key string) ([]byte, bool) {
 }
 
 func (c *FreeCache) Del(key string) {
-	innerCache.Del(util.Bytes(c.Key(key)))
+	c.inner.cache.Del(util.Bytes(c.Key(key)))
 }
 
 func (c *FreeCache) Key(key string) string {
@@ -103,3 +168,88 @@ func (c *FreeCache) Key(key string) string {
 
 	return fmt.Sprintf("%s:%s", c.innerKeyPrefix, key)
 }
+
+// Shared returns the freecache backing the instance.
+func (c *FreeCache) Shared() *SharedFreeCache {
+	return c.inner
+}
+
+// Capacity returns the memory size of the backing freecache.
+func (c *FreeCache) Capacity() Size {
+	return c.inner.Capacity()
+}
+
+// EntryCount returns the number of entries in the backing freecache.
+func (c *FreeCache) EntryCount() int64 {
+	return c.inner.EntryCount()
+}
+
+// EvictionCount returns the number of entries evicted from the backing freecache.
+func (c *FreeCache) EvictionCount() int64 {
+	return c.inner.EvictionCount()
+}
+
+// Metrics returns the metrics of the backing freecache.
+func (c *FreeCache) Metrics() Metrics {
+	return c.inner.Metrics()
+}
+
+// Clear removes the entries of the instance. Instances sharing the freecache
+// keep their entries unless the instance has no innerKeyPrefix.
+func (c *FreeCache) Clear() {
+	if c.innerKeyPrefix == "" {
+		c.inner.cache.Clear()
+		return
+	}
+
+	c.DelPrefix("")
+}
+
+// Len returns the number of entries of the instance. Without innerKeyPrefix,
+// it counts the expired entries not yet removed as well.
+func (c *FreeCache) Len() int {
+	if c.innerKeyPrefix == "" {
+		return int(c.inner.cache.EntryCount())
+	}
+
+	var n int
+	c.Range(func(string, []byte) bool {
+		n++
+		return true
+	})
+
+	return n
+}
+
+// Range calls fn for each unexpired entry of the instance until fn returns false.
+func (c *FreeCache) Range(fn func(key string, data []byte) bool) {
+	prefix := util.Bytes(c.Key(""))
+	if c.innerKeyPrefix == "" {
+		prefix = nil
+	}
+
+	it := c.inner.cache.NewIterator()
+	for e := it.Next(); e != nil; e = it.Next() {
+		if !bytes.HasPrefix(e.Key, prefix) {
+			continue
+		}
+		if !fn(string(e.Key[len(prefix):]), e.Value) {
+			return
+		}
+	}
+}
+
+// DelPrefix deletes the entries of the instance whose keys have the prefix.
+func (c *FreeCache) DelPrefix(prefix string) {
+	var keys []string
+	c.Range(func(key string, _ []byte) bool {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+
+	for _, key := range keys {
+		c.Del(key)
+	}
+}
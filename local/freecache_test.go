@@ -99,3 +99,102 @@ loop:
 		}
 	}
 }
+
+func TestFreeCacheIndependent(t *testing.T) {
+	c1 := NewFreeCache(1*MB, time.Minute)
+	c2 := NewFreeCache(2*MB, time.Minute)
+	assert.Equal(t, 1*MB, c1.Capacity())
+	assert.Equal(t, 2*MB, c2.Capacity())
+	assert.Equal(t, 256*MB, NewFreeCache(200*KB, time.Minute).Capacity())
+
+	c1.Set("key", []byte("value"))
+	_, exists := c2.Get("key")
+	assert.False(t, exists)
+	assert.Equal(t, int64(1), c1.EntryCount())
+	assert.Equal(t, int64(0), c2.EntryCount())
+
+	// fill c1 past its capacity, c2 is not affected.
+	c2.Set("key", []byte("value"))
+	value := make([]byte, 512)
+	for i := 0; i < 10000; i++ {
+		c1.Set(fmt.Sprintf("key-%d", i), value)
+	}
+	assert.Greater(t, c1.EvictionCount(), int64(0))
+	assert.Equal(t, int64(0), c2.EvictionCount())
+	val, exists := c2.Get("key")
+	assert.True(t, exists)
+	assert.Equal(t, []byte("value"), val)
+}
+
+func TestSharedFreeCache(t *testing.T) {
+	shared := NewSharedFreeCache(10 * MB)
+	c1 := shared.NewFreeCache(time.Minute, "c1")
+	c2 := shared.NewFreeCache(time.Minute, "c2")
+	assert.Same(t, shared, c1.Shared())
+	assert.Equal(t, 10*MB, c2.Capacity())
+
+	c1.Set("key", []byte("value1"))
+	c2.Set("key", []byte("value2"))
+	val, _ := c1.Get("key")
+	assert.Equal(t, []byte("value1"), val)
+	val, _ = c2.Get("key")
+	assert.Equal(t, []byte("value2"), val)
+	assert.Equal(t, int64(2), shared.EntryCount())
+	assert.Equal(t, int64(2), c1.EntryCount())
+}
+
+func TestFreeCacheMetrics(t *testing.T) {
+	cache := NewFreeCache(1*MB, time.Minute)
+	cache.Set("key", []byte("value1"))
+	cache.Set("key", []byte("value2"))
+	cache.Set("key2", []byte("value"))
+
+	m := cache.Metrics()
+	assert.Equal(t, int64(2), m.Entries)
+	assert.Equal(t, int64(1*MB), m.Capacity)
+	assert.Equal(t, int64(1*MB), m.Bytes)
+	assert.Equal(t, int64(1), m.Overwrites)
+	assert.Equal(t, int64(0), m.Evictions)
+}
+
+func TestFreeCacheRangeAndClear(t *testing.T) {
+	shared := NewSharedFreeCache(10 * MB)
+	c1 := shared.NewFreeCache(time.Minute, "c1")
+	c2 := shared.NewFreeCache(time.Minute, "c2")
+	c1.Set("product:1", []byte("p1"))
+	c1.Set("product:2", []byte("p2"))
+	c1.Set("user:1", []byte("u1"))
+	c2.Set("product:1", []byte("p1"))
+
+	assert.Equal(t, 3, c1.Len())
+	got := make(map[string]string)
+	c1.Range(func(key string, data []byte) bool {
+		got[key] = string(data)
+		return true
+	})
+	assert.Equal(t, map[string]string{"product:1": "p1", "product:2": "p2", "user:1": "u1"}, got)
+
+	var n int
+	c1.Range(func(string, []byte) bool {
+		n++
+		return false
+	})
+	assert.Equal(t, 1, n)
+
+	c1.DelPrefix("product:")
+	assert.Equal(t, 1, c1.Len())
+	_, exists := c1.Get("user:1")
+	assert.True(t, exists)
+	_, exists = c2.Get("product:1")
+	assert.True(t, exists)
+
+	c1.Clear()
+	assert.Equal(t, 0, c1.Len())
+	assert.Equal(t, 1, c2.Len())
+
+	c3 := NewFreeCache(1*MB, time.Minute)
+	c3.Set("key", []byte("value"))
+	assert.Equal(t, 1, c3.Len())
+	c3.Clear()
+	assert.Equal(t, 0, c3.Len())
+}
@@ -11,3 +11,48 @@ type Local interface {
 	// Del deletes the data associated with the specified key.
 	Del(key string)
 }
+
+type (
+	// MetricsReporter is an optional extension of Local that reports its memory
+	// and eviction metrics.
+	MetricsReporter interface {
+		// Metrics returns the current metrics of the local cache.
+		Metrics() Metrics
+	}
+
+	// Metrics is a snapshot of the memory and eviction metrics of a local cache.
+	// Evictions, Expirations, Overwrites and Rejections are cumulative.
+	Metrics struct {
+		Entries     int64 // Number of entries.
+		Bytes       int64 // Bytes used.
+		Capacity    int64 // Capacity in bytes, or in cost units for TinyLFU.
+		Evictions   int64 // Entries evicted to make room for new ones.
+		Expirations int64 // Entries removed on expiry.
+		Overwrites  int64 // Entries overwritten by a Set of the same key.
+		Rejections  int64 // Sets rejected by the admission policy or dropped.
+	}
+)
+
+type (
+	// Clearer is an optional extension of Local that removes all its entries.
+	Clearer interface {
+		Clear()
+	}
+
+	// Lener is an optional extension of Local that reports its number of entries.
+	Lener interface {
+		Len() int
+	}
+
+	// Ranger is an optional extension of Local that iterates its entries.
+	Ranger interface {
+		// Range calls fn for each entry until fn returns false. The order is not specified.
+		Range(fn func(key string, data []byte) bool)
+	}
+
+	// PrefixDeleter is an optional extension of Local that deletes the entries
+	// whose keys have the prefix.
+	PrefixDeleter interface {
+		DelPrefix(prefix string)
+	}
+)
@@ -1,49 +1,151 @@
 package local
 
 import (
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/dgraph-io/ristretto/v2"
+	"github.com/dgraph-io/ristretto/v2/z"
 	"github.com/mgtv-tech/jetcache-go/util"
 )
 
 const (
-	numCounters = 1e7 // number of keys to track frequency of (10M).
-	bufferItems = 64  // number of keys per Get buffer.
+	defaultNumCounters = 1e7       // number of keys to track frequency of (10M).
+	defaultBufferItems = 64        // number of keys per Get buffer.
+	maxPendingWrites   = 32 * 1024 // the size of the ristretto set buffer.
 )
 
-var _ Local = (*TinyLFU)(nil)
+var (
+	_ Local           = (*TinyLFU)(nil)
+	_ MetricsReporter = (*TinyLFU)(nil)
+	_ Clearer         = (*TinyLFU)(nil)
+	_ Lener           = (*TinyLFU)(nil)
+	_ Ranger          = (*TinyLFU)(nil)
+	_ PrefixDeleter   = (*TinyLFU)(nil)
+)
+
+type (
+	// TinyLFU is a local cache based on ristretto. By default the cost of an
+	// entry is 1, so that the size is the maximum number of entries. With
+	// WithByteCost, the cost of an entry is the byte size of its key and value,
+	// plus ristretto's per-entry overhead, so that the size is in bytes.
+	//
+	// By default Set waits for the entry to pass through ristretto's buffers, so
+	// a Get following a Set sees the value unless the admission policy rejected it.
+	// With WithAsyncWrite, Set returns immediately and keeps the value in a pending
+	// overlay until ristretto admits or rejects it, which keeps read-your-write
+	// for admitted values at the cost of serving a pending value until the
+	// next Wait even if it was evicted right after admission.
+	TinyLFU struct {
+		rand        *util.SafeRand
+		cache       *ristretto.Cache[string, []byte]
+		ttl         time.Duration
+		offset      time.Duration
+		asyncWrite  bool
+		byteCost    bool
+		evictions   atomic.Int64
+		expirations atomic.Int64
+		rejections  atomic.Int64
+		clearing    atomic.Bool
+		keysMu      sync.Mutex
+		keys        map[uint64]string // ristretto only keeps key hashes, track the keys for Range.
+		flushMu     sync.RWMutex      // write-locked by Wait so that no Set runs between the flush and the reset of pending.
+		pendingMu   sync.Mutex
+		pending     map[uint64]pendingWrite
+		pendingLen  atomic.Int64
+	}
+
+	// TinyLFUOption defines the method to customize a TinyLFU.
+	TinyLFUOption func(o *tinyLFUOptions)
+
+	tinyLFUOptions struct {
+		numCounters int64
+		bufferItems int64
+		asyncWrite  bool
+		byteCost    bool
+	}
+
+	pendingWrite struct {
+		value    []byte
+		expireAt time.Time
+	}
+)
+
+// WithNumCounters sets the number of keys to track frequency of, 10x the
+// expected number of entries is recommended. Default is 1e7.
+func WithNumCounters(numCounters int64) TinyLFUOption {
+	return func(o *tinyLFUOptions) {
+		o.numCounters = numCounters
+	}
+}
+
+// WithBufferItems sets the number of keys per Get buffer. Default is 64.
+func WithBufferItems(bufferItems int64) TinyLFUOption {
+	return func(o *tinyLFUOptions) {
+		o.bufferItems = bufferItems
+	}
+}
 
-type TinyLFU struct {
-	rand   *util.SafeRand
-	cache  *ristretto.Cache[string, []byte]
-	ttl    time.Duration
-	offset time.Duration
+// WithAsyncWrite makes Set return without waiting for the entry to pass
+// through ristretto's buffers, see TinyLFU.
+func WithAsyncWrite(asyncWrite bool) TinyLFUOption {
+	return func(o *tinyLFUOptions) {
+		o.asyncWrite = asyncWrite
+	}
 }
 
-func NewTinyLFU(size int, ttl time.Duration) *TinyLFU {
+// WithByteCost makes the size of the TinyLFU a number of bytes instead of
+// entries, see TinyLFU.
+func WithByteCost(byteCost bool) TinyLFUOption {
+	return func(o *tinyLFUOptions) {
+		o.byteCost = byteCost
+	}
+}
+
+// NewTinyLFU creates a new TinyLFU of the size in entries, or in bytes with
+// WithByteCost.
+func NewTinyLFU(size int, ttl time.Duration, opts ...TinyLFUOption) *TinyLFU {
 	const maxOffset = 10 * time.Second
 
+	o := tinyLFUOptions{
+		numCounters: defaultNumCounters,
+		bufferItems: defaultBufferItems,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	offset := ttl / 10
 	if offset > maxOffset {
 		offset = maxOffset
 	}
 
+	c := &TinyLFU{
+		rand:       util.NewSafeRand(),
+		ttl:        ttl,
+		offset:     offset,
+		asyncWrite: o.asyncWrite,
+		byteCost:   o.byteCost,
+		keys:       make(map[uint64]string),
+		pending:    make(map[uint64]pendingWrite),
+	}
 	cache, err := ristretto.NewCache[string, []byte](&ristretto.Config[string, []byte]{
-		NumCounters: numCounters,
-		MaxCost:     int64(size),
-		BufferItems: bufferItems,
+		NumCounters:        o.numCounters,
+		MaxCost:            int64(size),
+		BufferItems:        o.bufferItems,
+		IgnoreInternalCost: !o.byteCost,
+		Metrics:            true,
+		OnEvict:            c.onEvict,
+		OnReject:           c.onReject,
 	})
 	if err != nil {
 		panic(err)
 	}
+	c.cache = cache
 
-	return &TinyLFU{
-		rand:   util.NewSafeRand(),
-		cache:  cache,
-		ttl:    ttl,
-		offset: offset,
-	}
+	return c
 }
 
 func (c *TinyLFU) UseRandomizedTTL(offset time.Duration) {
@@ -56,21 +158,205 @@ func (c *TinyLFU) Set(key string, b []byte) {
 		ttl += time.Duration(c.rand.Int63n(int64(c.offset)))
 	}
 
-	c.cache.SetWithTTL(key, b, 1, ttl)
+	hash, _ := z.KeyToHash(key)
+	c.keysMu.Lock()
+	c.keys[hash] = key
+	c.keysMu.Unlock()
 
-	// wait for value to pass through buffers
-	c.cache.Wait()
+	if !c.asyncWrite {
+		if !c.cache.SetWithTTL(key, b, c.cost(key, b), ttl) {
+			c.dropped(hash)
+		}
+		// wait for value to pass through buffers
+		c.cache.Wait()
+		return
+	}
+
+	c.flushMu.RLock()
+	// add the pending write first, a rejection may happen as soon as the entry is buffered.
+	c.setPending(hash, b, ttl)
+	if !c.cache.SetWithTTL(key, b, c.cost(key, b), ttl) {
+		c.dropped(hash)
+	}
+	c.flushMu.RUnlock()
+
+	if c.pendingLen.Load() >= maxPendingWrites {
+		c.Wait()
+	}
 }
 
 func (c *TinyLFU) Get(key string) ([]byte, bool) {
 	val, ok := c.cache.Get(key)
-	if !ok {
-		return nil, false
+	if ok {
+		if c.pendingLen.Load() > 0 {
+			hash, _ := z.KeyToHash(key)
+			c.removePending(hash)
+		}
+		return val, true
 	}
 
-	return val, true
+	if c.pendingLen.Load() > 0 {
+		hash, _ := z.KeyToHash(key)
+		c.pendingMu.Lock()
+		w, ok := c.pending[hash]
+		c.pendingMu.Unlock()
+		if ok && (w.expireAt.IsZero() || time.Now().Before(w.expireAt)) {
+			return w.value, true
+		}
+	}
+
+	return nil, false
 }
 
 func (c *TinyLFU) Del(key string) {
+	hash, _ := z.KeyToHash(key)
+	c.removePending(hash)
 	c.cache.Del(key)
+	c.removeKey(hash)
+}
+
+// Wait waits for the pending writes to pass through ristretto's buffers.
+func (c *TinyLFU) Wait() {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.cache.Wait()
+	c.pendingMu.Lock()
+	c.pending = make(map[uint64]pendingWrite)
+	c.pendingLen.Store(0)
+	c.pendingMu.Unlock()
+}
+
+// Clear removes all the entries.
+func (c *TinyLFU) Clear() {
+	c.clearing.Store(true)
+	c.cache.Clear()
+	c.clearing.Store(false)
+
+	c.keysMu.Lock()
+	c.keys = make(map[uint64]string)
+	c.keysMu.Unlock()
+	c.pendingMu.Lock()
+	c.pending = make(map[uint64]pendingWrite)
+	c.pendingLen.Store(0)
+	c.pendingMu.Unlock()
+}
+
+// Len returns the number of entries.
+func (c *TinyLFU) Len() int {
+	c.keysMu.Lock()
+	defer c.keysMu.Unlock()
+
+	return len(c.keys)
+}
+
+// Range calls fn for each entry until fn returns false.
+func (c *TinyLFU) Range(fn func(key string, data []byte) bool) {
+	c.keysMu.Lock()
+	keys := make([]string, 0, len(c.keys))
+	for _, key := range c.keys {
+		keys = append(keys, key)
+	}
+	c.keysMu.Unlock()
+
+	for _, key := range keys {
+		if b, ok := c.Get(key); ok && !fn(key, b) {
+			return
+		}
+	}
+}
+
+// DelPrefix deletes the entries whose keys have the prefix.
+func (c *TinyLFU) DelPrefix(prefix string) {
+	c.keysMu.Lock()
+	var keys []string
+	for _, key := range c.keys {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	c.keysMu.Unlock()
+
+	for _, key := range keys {
+		c.Del(key)
+	}
+}
+
+// Metrics returns the metrics of the cache. Bytes and Capacity are in cost
+// units: entries by default, or bytes including ristretto's per-entry overhead
+// with WithByteCost.
+func (c *TinyLFU) Metrics() Metrics {
+	m := c.cache.Metrics
+	return Metrics{
+		Entries:     int64(m.KeysAdded() - m.KeysEvicted()),
+		Bytes:       int64(m.CostAdded() - m.CostEvicted()),
+		Capacity:    c.cache.MaxCost(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Overwrites:  int64(m.KeysUpdated()),
+		Rejections:  c.rejections.Load(),
+	}
+}
+
+func (c *TinyLFU) cost(key string, b []byte) int64 {
+	if !c.byteCost {
+		return 1
+	}
+
+	return int64(len(key) + len(b))
+}
+
+func (c *TinyLFU) setPending(hash uint64, b []byte, ttl time.Duration) {
+	c.pendingMu.Lock()
+	if _, ok := c.pending[hash]; !ok {
+		c.pendingLen.Add(1)
+	}
+	w := pendingWrite{value: b}
+	if ttl > 0 {
+		w.expireAt = time.Now().Add(ttl)
+	}
+	c.pending[hash] = w
+	c.pendingMu.Unlock()
+}
+
+func (c *TinyLFU) removePending(hash uint64) {
+	c.pendingMu.Lock()
+	if _, ok := c.pending[hash]; ok {
+		delete(c.pending, hash)
+		c.pendingLen.Add(-1)
+	}
+	c.pendingMu.Unlock()
+}
+
+func (c *TinyLFU) removeKey(hash uint64) {
+	c.keysMu.Lock()
+	delete(c.keys, hash)
+	c.keysMu.Unlock()
+}
+
+// dropped handles a Set dropped because ristretto's set buffer is full.
+func (c *TinyLFU) dropped(hash uint64) {
+	c.rejections.Add(1)
+	c.removePending(hash)
+	c.removeKey(hash)
+}
+
+func (c *TinyLFU) onEvict(item *ristretto.Item[[]byte]) {
+	c.removeKey(item.Key)
+	if c.clearing.Load() {
+		return
+	}
+
+	if !item.Expiration.IsZero() && !item.Expiration.After(time.Now()) {
+		c.expirations.Add(1)
+	} else {
+		c.evictions.Add(1)
+	}
+}
+
+// onReject is called when the admission policy rejects a new entry.
+func (c *TinyLFU) onReject(item *ristretto.Item[[]byte]) {
+	c.rejections.Add(1)
+	c.removePending(item.Key)
+	c.removeKey(item.Key)
 }
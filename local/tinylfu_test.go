@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
@@ -92,3 +93,125 @@ loop:
 		}
 	}
 }
+
+func TestTinyLFUMetrics(t *testing.T) {
+	counted := NewTinyLFU(1000, time.Minute)
+	counted.Set("key1", []byte("value1"))
+	counted.Set("key2", make([]byte, 2000))
+	m := counted.Metrics()
+	assert.Equal(t, int64(2), m.Entries)
+	assert.Equal(t, int64(2), m.Bytes)
+	assert.Equal(t, int64(1000), m.Capacity)
+
+	cache := NewTinyLFU(1000, time.Minute, WithByteCost(true))
+	cache.Set("key1", []byte("value1"))
+	cache.Set("key1", []byte("value"))
+	cache.Set("key2", []byte("value2"))
+
+	m = cache.Metrics()
+	assert.Equal(t, int64(2), m.Entries)
+	// the byte size of the keys and values plus ristretto's per-entry overhead.
+	assert.Equal(t, int64(len("key1value")+len("key2value2")+2*56), m.Bytes)
+	assert.Equal(t, int64(1000), m.Capacity)
+	assert.Equal(t, int64(1), m.Overwrites)
+
+	cache.Del("key1")
+	cache.Del("key2")
+	cache.cache.Wait()
+	m = cache.Metrics()
+	assert.Equal(t, int64(0), m.Entries)
+	assert.Equal(t, int64(0), m.Bytes)
+}
+
+func TestTinyLFURangeAndClear(t *testing.T) {
+	cache := NewTinyLFU(1000, time.Minute)
+	cache.Set("product:1", []byte("p1"))
+	cache.Set("product:2", []byte("p2"))
+	cache.Set("user:1", []byte("u1"))
+
+	assert.Equal(t, 3, cache.Len())
+	got := make(map[string]string)
+	cache.Range(func(key string, data []byte) bool {
+		got[key] = string(data)
+		return true
+	})
+	assert.Equal(t, map[string]string{"product:1": "p1", "product:2": "p2", "user:1": "u1"}, got)
+
+	cache.DelPrefix("product:")
+	assert.Equal(t, 1, cache.Len())
+	_, exists := cache.Get("product:1")
+	assert.False(t, exists)
+	_, exists = cache.Get("user:1")
+	assert.True(t, exists)
+
+	cache.Clear()
+	assert.Equal(t, 0, cache.Len())
+	_, exists = cache.Get("user:1")
+	assert.False(t, exists)
+	assert.Equal(t, int64(0), cache.Metrics().Evictions)
+}
+
+func TestTinyLFUOptions(t *testing.T) {
+	cache := NewTinyLFU(1000, time.Minute, WithNumCounters(100), WithBufferItems(8))
+	cache.Set("key", []byte("value"))
+	val, ok := cache.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value"), val)
+}
+
+func TestTinyLFURejection(t *testing.T) {
+	cache := NewTinyLFU(100, time.Minute, WithByteCost(true))
+	cache.Set("key", make([]byte, 100))
+	_, ok := cache.Get("key")
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), cache.Metrics().Rejections)
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestTinyLFUAsyncWrite(t *testing.T) {
+	cache := NewTinyLFU(1000, time.Minute, WithAsyncWrite(true), WithByteCost(true))
+
+	// read-your-write before the entry passes through the buffers.
+	cache.Set("key1", []byte("value1"))
+	val, ok := cache.Get("key1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value1"), val)
+
+	cache.Set("key2", []byte("value2"))
+	cache.Del("key2")
+	_, ok = cache.Get("key2")
+	assert.False(t, ok)
+
+	// rejected values do not look cached.
+	cache.Set("key3", make([]byte, 1000))
+	cache.Wait()
+	_, ok = cache.Get("key3")
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), cache.Metrics().Rejections)
+
+	val, ok = cache.Get("key1")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("value1"), val)
+	assert.Equal(t, int64(0), cache.pendingLen.Load())
+}
+
+func TestTinyLFUAsyncWriteConcurrent(t *testing.T) {
+	cache := NewTinyLFU(10*int(MB), time.Minute, WithAsyncWrite(true))
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		g := g
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprintf("key-%d-%d", g, i)
+				cache.Set(key, []byte(key))
+				if val, ok := cache.Get(key); ok {
+					assert.Equal(t, key, string(val))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/mgtv-tech/jetcache-go/local"
+	"github.com/mgtv-tech/jetcache-go/logger"
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+var ErrManagerClosed = errors.New("cache: manager is closed")
+
+type (
+	// Manager creates caches from named configs, shares local and remote backends
+	// between them, routes sync events to them and closes them together.
+	Manager struct {
+		mu      sync.RWMutex
+		remotes map[string]remote.Remote
+		locals  map[string]local.Local
+		opts    []Option
+		caches  map[string]Cache
+		order   []string // names in creation order, closed in reverse.
+		closed  bool
+	}
+
+	// ManagerOption defines the method to customize a Manager.
+	ManagerOption func(m *Manager)
+
+	// CacheConfig is the named config of a cache created by a Manager.
+	CacheConfig struct {
+		Name    string   // Cache name, unique within the Manager.
+		Remote  string   // Name of the shared remote registered by WithSharedRemote. Empty for none.
+		Local   string   // Name of the shared local registered by WithSharedLocal. Empty for none.
+		Options []Option // Options applied after the common options.
+	}
+)
+
+// WithSharedRemote registers a remote backend that caches can refer to by name.
+func WithSharedRemote(name string, remote remote.Remote) ManagerOption {
+	return func(m *Manager) {
+		m.remotes[name] = remote
+	}
+}
+
+// WithSharedLocal registers a local backend that caches can refer to by name.
+// Caches sharing a local backend share its capacity, so their keys must not overlap.
+// The operations on the whole local backend, ClearLocalCache, LocalCacheLen and
+// RangeLocalCache, fail with ErrLocalShared for these caches, and their Stats
+// leave out the local metrics, which are read from the backend instead.
+func WithSharedLocal(name string, local local.Local) ManagerOption {
+	return func(m *Manager) {
+		m.locals[name] = local
+	}
+}
+
+// withSharedLocal marks the local of a cache as shared, see WithSharedLocal.
+func withSharedLocal() Option {
+	return func(o *Options) {
+		o.sharedLocal = true
+	}
+}
+
+// WithCommonOptions sets the options applied to every cache, such as
+// WithSourceId, WithSyncLocal or WithEventHandler.
+func WithCommonOptions(opts ...Option) ManagerOption {
+	return func(m *Manager) {
+		m.opts = append(m.opts, opts...)
+	}
+}
+
+// NewManager creates a new Manager.
+func NewManager(opts ...ManagerOption) *Manager {
+	m := &Manager{
+		remotes: make(map[string]remote.Remote),
+		locals:  make(map[string]local.Local),
+		caches:  make(map[string]Cache),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// New creates a cache from the config and registers it under its name.
+func (m *Manager) New(config CacheConfig) (Cache, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+	if config.Name == "" {
+		return nil, errors.New("cache: config name is empty")
+	}
+	if _, ok := m.caches[config.Name]; ok {
+		return nil, fmt.Errorf("cache: cache %q already exists", config.Name)
+	}
+
+	opts := make([]Option, 0, len(m.opts)+len(config.Options)+3)
+	opts = append(opts, m.opts...)
+	opts = append(opts, WithName(config.Name))
+	if config.Remote != "" {
+		r, ok := m.remotes[config.Remote]
+		if !ok {
+			return nil, fmt.Errorf("cache: remote %q is not registered", config.Remote)
+		}
+		opts = append(opts, WithRemote(r))
+	}
+	if config.Local != "" {
+		l, ok := m.locals[config.Local]
+		if !ok {
+			return nil, fmt.Errorf("cache: local %q is not registered", config.Local)
+		}
+		opts = append(opts, WithLocal(l), withSharedLocal())
+	}
+	opts = append(opts, config.Options...)
+
+	c := New(opts...)
+	m.caches[config.Name] = c
+	m.order = append(m.order, config.Name)
+
+	return c, nil
+}
+
+// Get returns the cache registered under the name.
+func (m *Manager) Get(name string) (Cache, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.caches[name]
+	return c, ok
+}
+
+// Caches returns the caches in creation order.
+func (m *Manager) Caches() []Cache {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	caches := make([]Cache, 0, len(m.order))
+	for _, name := range m.order {
+		caches = append(caches, m.caches[name])
+	}
+
+	return caches
+}
+
+// HandleEvent routes a sync event received from another process to the cache
+// named by Event.CacheName.
+func (m *Manager) HandleEvent(event *Event) {
+	if event == nil {
+		return
+	}
+
+	c, ok := m.Get(event.CacheName)
+	if !ok {
+		logger.Debug("manager#HandleEvent cache(%s) not found", event.CacheName)
+		return
+	}
+
+	if h, ok := c.(LocalController); ok {
+		h.HandleEvent(event)
+	}
+}
+
+// Close closes the caches in reverse creation order.
+func (m *Manager) Close() {
+	for _, name := range m.close() {
+		m.caches[name].Close()
+	}
+}
+
+// Shutdown gracefully closes the caches in reverse creation order, see
+// Shutdowner. The caches not implementing Shutdowner are closed by Close.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	var errs error
+	for _, name := range m.close() {
+		s, ok := m.caches[name].(Shutdowner)
+		if !ok {
+			m.caches[name].Close()
+			continue
+		}
+		if err := s.Shutdown(ctx); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("cache(%s).Shutdown error(%w)", name, err))
+		}
+	}
+
+	return errs
+}
+
+// close marks the manager closed and returns the names of the caches to close
+// in order. The caches are no longer modified once the manager is closed.
+func (m *Manager) close() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed = true
+	names := make([]string, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		names = append(names, m.order[i])
+	}
+
+	return names
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRdb()
+	m := NewManager(
+		WithSharedRemote("redis", remote.NewGoRedisV9Adapter(rdb)),
+		WithSharedLocal("lfu", localNew(tinyLFU)),
+		WithCommonOptions(WithSourceId("pod1"), WithRemoteExpiry(time.Minute)))
+
+	user, err := m.New(CacheConfig{Name: "user", Remote: "redis", Local: "lfu"})
+	assert.NoError(t, err)
+	order, err := m.New(CacheConfig{Name: "order", Remote: "redis", Options: []Option{WithRemoteExpiry(time.Hour)}})
+	assert.NoError(t, err)
+
+	t.Run("config", func(t *testing.T) {
+		assert.Equal(t, TypeBoth, user.CacheType())
+		assert.Equal(t, "pod1", user.(Inspector).Config().SourceID)
+		assert.Equal(t, time.Minute, user.(Inspector).Config().RemoteExpiry)
+		assert.Equal(t, TypeRemote, order.CacheType())
+		assert.Equal(t, time.Hour, order.(Inspector).Config().RemoteExpiry)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := m.New(CacheConfig{Name: "user"})
+		assert.EqualError(t, err, `cache: cache "user" already exists`)
+		_, err = m.New(CacheConfig{})
+		assert.Error(t, err)
+		_, err = m.New(CacheConfig{Name: "any", Remote: "any"})
+		assert.EqualError(t, err, `cache: remote "any" is not registered`)
+		_, err = m.New(CacheConfig{Name: "any", Local: "any"})
+		assert.EqualError(t, err, `cache: local "any" is not registered`)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		c, ok := m.Get("user")
+		assert.True(t, ok)
+		assert.Equal(t, user, c)
+		_, ok = m.Get("any")
+		assert.False(t, ok)
+		assert.Equal(t, []Cache{user, order}, m.Caches())
+	})
+
+	t.Run("handle event", func(t *testing.T) {
+		assert.NoError(t, user.Set(ctx, "k1", Value("v1")))
+		values, _ := user.(Inspector).Peek(ctx, "k1")
+		assert.True(t, values[0].Found)
+
+		// events sent by this process are ignored
+		m.HandleEvent(&Event{CacheName: "user", SourceID: "pod1", EventType: EventTypeSet, Keys: []string{"k1"}})
+		values, _ = user.(Inspector).Peek(ctx, "k1")
+		assert.True(t, values[0].Found)
+
+		m.HandleEvent(&Event{CacheName: "any", SourceID: "pod2", EventType: EventTypeSet, Keys: []string{"k1"}})
+		m.HandleEvent(nil)
+		values, _ = user.(Inspector).Peek(ctx, "k1")
+		assert.True(t, values[0].Found)
+
+		m.HandleEvent(&Event{CacheName: "user", SourceID: "pod2", EventType: EventTypeSet, Keys: []string{"k1"}})
+		values, _ = user.(Inspector).Peek(ctx, "k1")
+		assert.False(t, values[0].Found)
+		assert.True(t, values[1].Found)
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		assert.NoError(t, m.Shutdown(ctx))
+		_, err := m.New(CacheConfig{Name: "any"})
+		assert.Equal(t, ErrManagerClosed, err)
+		m.Close()
+	})
+}
+
+func TestManagerSharedLocal(t *testing.T) {
+	ctx := context.Background()
+	shared := localNew(freeCache)
+	m := NewManager(WithSharedLocal("free", shared))
+	defer m.Close()
+
+	user, err := m.New(CacheConfig{Name: "user", Local: "free"})
+	assert.NoError(t, err)
+	order, err := m.New(CacheConfig{Name: "order", Local: "free"})
+	assert.NoError(t, err)
+	assert.NoError(t, user.Set(ctx, "user:1", Value("v1")))
+	assert.NoError(t, order.Set(ctx, "order:1", Value("v1")))
+
+	// the operations on the whole local backend would affect both caches.
+	assert.ErrorIs(t, user.(LocalController).ClearLocalCache(), ErrLocalShared)
+	_, err = user.(LocalController).LocalCacheLen()
+	assert.ErrorIs(t, err, ErrLocalShared)
+	assert.ErrorIs(t, user.(LocalController).RangeLocalCache(func(string, []byte) bool { return true }), ErrLocalShared)
+	assert.True(t, order.Exists(ctx, "order:1"))
+
+	assert.NoError(t, user.(LocalController).DeleteFromLocalCacheByPrefix("user:"))
+	assert.False(t, user.Exists(ctx, "user:1"))
+	assert.True(t, order.Exists(ctx, "order:1"))
+
+	assert.Zero(t, user.(Inspector).Stats().Local)
+	assert.Zero(t, order.(Inspector).Stats().Local)
+
+	// a private local is not shared.
+	private, err := m.New(CacheConfig{Name: "private", Options: []Option{WithLocal(localNew(freeCache))}})
+	assert.NoError(t, err)
+	assert.NoError(t, private.(LocalController).ClearLocalCache())
+	assert.NotZero(t, private.(Inspector).Stats().Local.Capacity)
+}
@@ -0,0 +1,187 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go/encoding"
+	"github.com/mgtv-tech/jetcache-go/util"
+)
+
+const (
+	// PlaceholderLegacy writes the legacy "*" placeholder and reads both
+	// placeholders, so that the instances of previous versions still read the
+	// placeholders written. A cached "*" string value is read as not found.
+	PlaceholderLegacy PlaceholderMode = iota
+	// PlaceholderUpgrade writes the new placeholder and reads both. Switch to it
+	// once no instance of a previous version is left.
+	PlaceholderUpgrade
+	// PlaceholderNew writes and reads the new placeholder only, so that a "*"
+	// string value can be cached. Switch to it once the legacy placeholders
+	// written before PlaceholderUpgrade have expired.
+	PlaceholderNew
+)
+
+var (
+	// notFoundPlaceholder is cached for the keys known to be absent. It is the
+	// encoding.FormatNotFound header, so that it cannot collide with the other
+	// headers nor with a one-byte "*" string value.
+	notFoundPlaceholder = encoding.AppendHeader(nil, encoding.FormatNotFound)
+	// legacyPlaceholder is the placeholder written by previous versions, see PlaceholderMode.
+	legacyPlaceholder = []byte("*")
+)
+
+// PlaceholderMode selects the not found placeholder written and read, to roll
+// out the new placeholder across instances, see WithPlaceholderMode.
+type PlaceholderMode int
+
+// placeholder returns the not found placeholder written.
+func (c *jetCache) placeholder() []byte {
+	if c.placeholderMode == PlaceholderLegacy {
+		return legacyPlaceholder
+	}
+
+	return notFoundPlaceholder
+}
+
+func (c *jetCache) isNotFoundPlaceholder(b []byte) bool {
+	return bytes.Equal(b, notFoundPlaceholder) ||
+		(c.placeholderMode != PlaceholderNew && bytes.Equal(b, legacyPlaceholder))
+}
+
+func (c *jetCache) SetNotFound(ctx context.Context, ttl time.Duration, keys ...string) error {
+	if c.errNotFound == nil {
+		return ErrNotFoundDisabled
+	}
+
+	var errs error
+	for _, key := range keys {
+		c.delLocalTiers(key)
+		if err := c.setNotFound(ctx, key, false, ttl); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("SetNotFound#c.setNotFound(%s) error(%v)", key, err))
+		}
+	}
+	c.send(EventTypeSet, keys...)
+
+	return errs
+}
+
+func (c *jetCache) IsNotFoundCached(ctx context.Context, key string) (bool, error) {
+	if c.local != nil {
+		if b, ok := c.local.Get(key); ok {
+			return c.isNotFoundPlaceholder(b), nil
+		}
+	}
+
+	if c.remote == nil {
+		if c.local == nil {
+			return false, ErrRemoteLocalBothNil
+		}
+		return false, nil
+	}
+
+	s, err := c.remote.Get(ctx, key)
+	if errors.Is(err, c.remote.Nil()) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	b, ok := c.unseal(key, util.Bytes(s))
+
+	return ok && c.isNotFoundPlaceholder(b), nil
+}
+
+// DeleteNotFound checks and deletes each key in two steps, so a value set
+// in between may be deleted too, and is then reloaded.
+func (c *jetCache) DeleteNotFound(ctx context.Context, keys ...string) error {
+	if c.local == nil && c.remote == nil {
+		return ErrRemoteLocalBothNil
+	}
+
+	var errs error
+	deleted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		found := false
+		if c.local != nil {
+			if b, ok := c.local.Get(key); ok && c.isNotFoundPlaceholder(b) {
+				c.local.Del(key)
+				found = true
+			}
+		}
+
+		if c.remote != nil {
+			s, err := c.remote.Get(ctx, key)
+			if err != nil && !errors.Is(err, c.remote.Nil()) {
+				errs = errors.Join(errs, fmt.Errorf("DeleteNotFound#c.remote.Get(%s) error(%v)", key, err))
+				continue
+			}
+			if b, ok := c.unseal(key, util.Bytes(s)); err == nil && ok && c.isNotFoundPlaceholder(b) {
+				if _, err = c.remote.Del(ctx, key); err != nil {
+					errs = errors.Join(errs, fmt.Errorf("DeleteNotFound#c.remote.Del(%s) error(%v)", key, err))
+					continue
+				}
+				found = true
+			}
+		}
+
+		if found {
+			deleted = append(deleted, key)
+		}
+	}
+	if len(deleted) > 0 {
+		c.send(EventTypeDelete, deleted...)
+	}
+
+	return errs
+}
+
+// setNotFound caches the placeholder of the key for the ttl, or for the
+// notFoundExpiry plus a random offset if the ttl is not positive.
+func (c *jetCache) setNotFound(ctx context.Context, key string, skipLocal bool, ttl time.Duration) error {
+	if c.local != nil && !skipLocal {
+		c.local.Set(key, c.placeholder())
+	}
+
+	if c.remote == nil {
+		if c.local == nil {
+			return ErrRemoteLocalBothNil
+		}
+		return nil
+	}
+
+	if ttl <= 0 {
+		ttl = c.notFoundExpiry + time.Duration(c.safeRand.Int63n(int64(c.offset)))
+	}
+
+	return c.remote.SetEX(ctx, key, c.seal(key, c.placeholder()), ttl)
+}
+
+// SetNotFound caches the `ids` of the `key` as known-absent for the ttl, or the
+// notFoundExpiry if the ttl is 0, see Cache.SetNotFound.
+func (w *T[K, V]) SetNotFound(ctx context.Context, key string, ids []K, ttl time.Duration) error {
+	return w.Cache.(*jetCache).SetNotFound(ctx, ttl, w.keys(key, ids)...)
+}
+
+// IsNotFoundCached reports whether the `id` of the `key` is cached as known-absent.
+func (w *T[K, V]) IsNotFoundCached(ctx context.Context, key string, id K) (bool, error) {
+	return w.Cache.(*jetCache).IsNotFoundCached(ctx, w.keys(key, []K{id})[0])
+}
+
+// DeleteNotFound deletes the `ids` of the `key` cached as known-absent, see Cache.DeleteNotFound.
+func (w *T[K, V]) DeleteNotFound(ctx context.Context, key string, ids []K) error {
+	return w.Cache.(*jetCache).DeleteNotFound(ctx, w.keys(key, ids)...)
+}
+
+func (w *T[K, V]) keys(key string, ids []K) []string {
+	c := w.Cache.(*jetCache)
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, fmt.Sprintf("%s%s%v", key, c.separator, id))
+	}
+
+	return keys
+}
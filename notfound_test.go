@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+func TestNotFound(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRdb()
+	c := New(WithName("notFound"), WithRemote(remote.NewGoRedisV9Adapter(rdb)), WithLocal(localNew(freeCache)),
+		WithErrNotFound(errTestNotFound))
+	defer c.Close()
+
+	t.Run("set not found", func(t *testing.T) {
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		assert.NoError(t, c.(NotFoundCache).SetNotFound(ctx, 10*time.Second, "key1", "key2"))
+
+		var val string
+		assert.ErrorIs(t, c.Get(ctx, "key1", &val), errTestNotFound)
+		err := c.Once(ctx, "key2", Value(&val), Do(func(context.Context) (any, error) {
+			t.Fatal("not found keys are not loaded")
+			return nil, nil
+		}))
+		assert.ErrorIs(t, err, errTestNotFound)
+		assert.InDelta(t, 10*time.Second, rdb.TTL(ctx, "key1").Val(), float64(time.Second))
+
+		ok, err := c.(NotFoundCache).IsNotFoundCached(ctx, "key1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		c.DeleteFromLocalCache("key1")
+		ok, err = c.(NotFoundCache).IsNotFoundCached(ctx, "key1")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		assert.NoError(t, c.Set(ctx, "key3", Value("value3")))
+		assert.NoError(t, c.(NotFoundCache).DeleteNotFound(ctx, "key1", "key2", "key3", "key4"))
+
+		for _, key := range []string{"key1", "key2", "key3", "key4"} {
+			ok, err := c.(NotFoundCache).IsNotFoundCached(ctx, key)
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		}
+		var val string
+		assert.NoError(t, c.Get(ctx, "key3", &val))
+		assert.Equal(t, "value3", val)
+		assert.ErrorIs(t, c.Get(ctx, "key1", &val), ErrCacheMiss)
+	})
+
+	t.Run("not found ttl", func(t *testing.T) {
+		var val string
+		err := c.Once(ctx, "key5", Value(&val), NotFoundTTL(30*time.Second), Do(func(context.Context) (any, error) {
+			return nil, errTestNotFound
+		}))
+		assert.ErrorIs(t, err, errTestNotFound)
+		assert.InDelta(t, 30*time.Second, rdb.TTL(ctx, "key5").Val(), float64(time.Second))
+	})
+
+	t.Run("star value", func(t *testing.T) {
+		c := New(WithName("notFoundNew"), WithRemote(remote.NewGoRedisV9Adapter(rdb)), WithLocal(localNew(freeCache)),
+			WithErrNotFound(errTestNotFound), WithPlaceholderMode(PlaceholderNew))
+		defer c.Close()
+
+		assert.NoError(t, c.Set(ctx, "key6", Value("*")))
+		var val string
+		assert.NoError(t, c.Get(ctx, "key6", &val))
+		assert.Equal(t, "*", val)
+		c.DeleteFromLocalCache("key6")
+		val = ""
+		assert.NoError(t, c.Get(ctx, "key6", &val))
+		assert.Equal(t, "*", val)
+
+		ok, err := c.(NotFoundCache).IsNotFoundCached(ctx, "key6")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+
+		val = ""
+		assert.NoError(t, c.Once(ctx, "key7", Value(&val), Do(func(context.Context) (any, error) {
+			return "*", nil
+		})))
+		assert.Equal(t, "*", val)
+		c.DeleteFromLocalCache("key7")
+		val = ""
+		assert.NoError(t, c.Once(ctx, "key7", Value(&val), Do(func(context.Context) (any, error) {
+			t.Fatal("cached keys are not loaded")
+			return nil, nil
+		})))
+		assert.Equal(t, "*", val)
+
+		// the "*" value still collides with the legacy placeholder in the
+		// other modes.
+		for _, mode := range []PlaceholderMode{PlaceholderLegacy, PlaceholderUpgrade} {
+			legacy := New(WithName("notFoundLegacy"), WithRemote(remote.NewGoRedisV9Adapter(rdb)),
+				WithErrNotFound(errTestNotFound), WithPlaceholderMode(mode))
+			assert.ErrorIs(t, legacy.Get(ctx, "key6", &val), errTestNotFound)
+			legacy.Close()
+		}
+	})
+
+	t.Run("generic", func(t *testing.T) {
+		cacheT := NewT[int, string](c)
+		assert.NoError(t, cacheT.SetNotFound(ctx, "generic", []int{1, 2}, 0))
+		ret := cacheT.MGet(ctx, "generic", []int{1, 2, 3}, func(_ context.Context, ids []int) (map[int]string, error) {
+			assert.Equal(t, []int{3}, ids)
+			return map[int]string{3: "value3"}, nil
+		})
+		assert.Equal(t, map[int]string{3: "value3"}, ret)
+
+		ok, err := cacheT.IsNotFoundCached(ctx, "generic", 1)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.NoError(t, cacheT.DeleteNotFound(ctx, "generic", []int{1}))
+		ok, err = cacheT.IsNotFoundCached(ctx, "generic", 1)
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		ok, err = cacheT.IsNotFoundCached(ctx, "generic", 2)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
+
+func TestNotFoundErrors(t *testing.T) {
+	ctx := context.Background()
+
+	c := New(WithName("notFoundDisabled"), WithLocal(localNew(freeCache)))
+	defer c.Close()
+	assert.ErrorIs(t, c.(NotFoundCache).SetNotFound(ctx, 0, "key"), ErrNotFoundDisabled)
+
+	nilCache := New(WithName("notFoundNil"), WithErrNotFound(errTestNotFound))
+	defer nilCache.Close()
+	_, err := nilCache.(NotFoundCache).IsNotFoundCached(ctx, "key")
+	assert.ErrorIs(t, err, ErrRemoteLocalBothNil)
+	assert.ErrorIs(t, nilCache.(NotFoundCache).DeleteNotFound(ctx, "key"), ErrRemoteLocalBothNil)
+	assert.Error(t, nilCache.(NotFoundCache).SetNotFound(ctx, 0, "key"))
+
+	localCache := New(WithName("notFoundLocal"), WithLocal(localNew(freeCache)), WithErrNotFound(errTestNotFound))
+	defer localCache.Close()
+	assert.NoError(t, localCache.(NotFoundCache).SetNotFound(ctx, 0, "key"))
+	ok, err := localCache.(NotFoundCache).IsNotFoundCached(ctx, "key")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = localCache.(NotFoundCache).IsNotFoundCached(ctx, "other")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestPlaceholderMode(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRdb()
+	newCache := func(mode PlaceholderMode) Cache {
+		return New(WithName("placeholder"), WithRemote(remote.NewGoRedisV9Adapter(rdb)), WithErrNotFound(errTestNotFound),
+			WithPlaceholderMode(mode))
+	}
+	legacy, upgrade, strict := newCache(PlaceholderLegacy), newCache(PlaceholderUpgrade), newCache(PlaceholderNew)
+	defer legacy.Close()
+	defer upgrade.Close()
+	defer strict.Close()
+
+	// the instances of previous versions read "*" only.
+	assert.NoError(t, legacy.(NotFoundCache).SetNotFound(ctx, time.Minute, "legacy"))
+	assert.Equal(t, "*", rdb.Get(ctx, "legacy").Val())
+	assert.NoError(t, upgrade.(NotFoundCache).SetNotFound(ctx, time.Minute, "new"))
+	assert.Equal(t, string(notFoundPlaceholder), rdb.Get(ctx, "new").Val())
+
+	var val string
+	for _, c := range []Cache{legacy, upgrade} {
+		for _, key := range []string{"legacy", "new"} {
+			assert.ErrorIs(t, c.Get(ctx, key, &val), errTestNotFound)
+		}
+	}
+	assert.ErrorIs(t, strict.Get(ctx, "new", &val), errTestNotFound)
+	assert.NoError(t, strict.Get(ctx, "legacy", &val))
+	assert.Equal(t, "*", val)
+}
@@ -0,0 +1,428 @@
+package cache
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+
+	"github.com/mgtv-tech/jetcache-go/logger"
+	"github.com/mgtv-tech/jetcache-go/util"
+)
+
+const (
+	lockKeySuffix = "_#RL#"
+
+	// maxRefreshIdle bounds how long the scheduler sleeps when no task is queued.
+	maxRefreshIdle = time.Minute
+)
+
+// refreshQueue is a min-heap of refresh tasks ordered by their next refresh time.
+type refreshQueue []*refreshTask
+
+func (q refreshQueue) Len() int {
+	return len(q)
+}
+
+func (q refreshQueue) Less(i, j int) bool {
+	return q[i].nextRefreshTime.Before(q[j].nextRefreshTime)
+}
+
+func (q refreshQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *refreshQueue) Push(x any) {
+	task := x.(*refreshTask)
+	task.index = len(*q)
+	*q = append(*q, task)
+}
+
+func (q *refreshQueue) Pop() any {
+	old := *q
+	n := len(old)
+	task := old[n-1]
+	old[n-1] = nil
+	task.index = -1
+	*q = old[:n-1]
+	return task
+}
+
+// RefreshTaskInfo describes a registered refresh task.
+type RefreshTaskInfo struct {
+	Key             string
+	RefreshDuration time.Duration
+	LastAccessTime  time.Time
+	LastRefreshTime time.Time // zero if the key has not been refreshed yet.
+	LastErr         error     // error of the last refresh, nil if it succeeded.
+	NextRefreshTime time.Time // zero while the key is refreshing.
+}
+
+func (c *jetCache) RefreshTasks() []RefreshTaskInfo {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	var infos []RefreshTaskInfo
+	c.refreshTaskMap.Range(func(key, val any) bool {
+		task := val.(*refreshTask)
+		info := RefreshTaskInfo{
+			Key:             task.key,
+			RefreshDuration: task.refreshDuration,
+		}
+		if task.index >= 0 {
+			info.NextRefreshTime = task.nextRefreshTime
+		}
+		task.mu.Lock()
+		info.LastAccessTime = task.lastAccessTime
+		info.LastRefreshTime = task.lastRefreshTime
+		info.LastErr = task.lastErr
+		task.mu.Unlock()
+
+		infos = append(infos, info)
+		return true
+	})
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Key < infos[j].Key
+	})
+
+	return infos
+}
+
+func (c *jetCache) RefreshNow(ctx context.Context, key string) error {
+	ins, ok := c.refreshTaskMap.Load(key)
+	if !ok {
+		return ErrRefreshTaskNotFound
+	}
+
+	task := ins.(*refreshTask)
+	c.unschedule(task)
+
+	now := time.Now()
+	err := c.load(ctx, task)
+	task.done(now, err)
+	c.schedule(task, time.Now())
+
+	return err
+}
+
+func (c *jetCache) CancelRefresh(key string) {
+	c.cancel(key)
+}
+
+func (c *jetCache) PauseRefresh() {
+	c.refreshPaused.Store(true)
+}
+
+func (c *jetCache) ResumeRefresh() {
+	c.refreshPaused.Store(false)
+}
+
+func (c *jetCache) addOrUpdateRefreshTask(item *item) {
+	if !item.refresh {
+		return
+	}
+
+	refreshDuration := item.getRefreshDuration(c.refreshDuration)
+	if refreshDuration <= 0 {
+		return
+	}
+
+	if ins, ok := c.refreshTaskMap.Load(item.key); ok {
+		ins.(*refreshTask).access(time.Now())
+	} else if ins, loaded := c.refreshTaskMap.LoadOrStore(item.key, item.toRefreshTask(refreshDuration)); loaded {
+		ins.(*refreshTask).access(time.Now())
+	} else {
+		c.refreshOnce.Do(c.startRefresh)
+		c.schedule(ins.(*refreshTask), time.Now())
+	}
+}
+
+func (c *jetCache) cancel(key any) {
+	if ins, ok := c.refreshTaskMap.LoadAndDelete(key); ok {
+		c.unschedule(ins.(*refreshTask))
+	}
+}
+
+func (c *jetCache) stopRefresh() {
+	c.cancelFunc()
+	c.stopTimers()
+	c.refreshTaskMap.Range(func(key, val any) bool {
+		c.cancel(key)
+		return true
+	})
+}
+
+// schedule queues a registered task to be refreshed one refreshDuration after from.
+// The delay is shortened by a random jitter of up to a tenth, so that keys registered
+// together spread their loads while never being refreshed later than asked.
+func (c *jetCache) schedule(task *refreshTask, from time.Time) {
+	delay := task.refreshDuration
+	if jitter := int64(delay / 10); jitter > 0 {
+		delay -= time.Duration(c.safeRand.Int63n(jitter))
+	}
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	// The task may have been canceled while it was refreshing.
+	if ins, ok := c.refreshTaskMap.Load(task.key); !ok || ins != task {
+		return
+	}
+
+	// The task may also have been queued by a concurrent RefreshNow.
+	task.nextRefreshTime = from.Add(delay)
+	if task.index >= 0 {
+		heap.Fix(&c.refreshQueue, task.index)
+	} else {
+		heap.Push(&c.refreshQueue, task)
+	}
+	if task.index == 0 {
+		select {
+		case c.refreshWakeCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *jetCache) unschedule(task *refreshTask) {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if task.index >= 0 {
+		heap.Remove(&c.refreshQueue, task.index)
+	}
+}
+
+// dueTasks pops the tasks whose refresh time has come. Tasks that have not been
+// accessed for stopRefreshAfterLastAccess are canceled instead of returned.
+func (c *jetCache) dueTasks(now time.Time) []*refreshTask {
+	var tasks []*refreshTask
+	c.refreshMu.Lock()
+	for len(c.refreshQueue) > 0 && !c.refreshQueue[0].nextRefreshTime.After(now) {
+		tasks = append(tasks, heap.Pop(&c.refreshQueue).(*refreshTask))
+	}
+	c.refreshMu.Unlock()
+
+	due := tasks[:0]
+	for _, task := range tasks {
+		if !task.accessedSince(now.Add(-c.stopRefreshAfter(task))) {
+			logger.Debug("cancel refresh key: %s", task.key)
+			c.refreshTaskMap.CompareAndDelete(task.key, task)
+			continue
+		}
+		due = append(due, task)
+	}
+
+	return due
+}
+
+// stopRefreshAfter returns how long the task keeps refreshing without being accessed.
+// It is at least one refresh interval, so that keys refreshed less often than
+// stopRefreshAfterLastAccess are not canceled on their first run.
+func (c *jetCache) stopRefreshAfter(task *refreshTask) time.Duration {
+	if least := task.refreshDuration + time.Second; c.stopRefreshAfterLastAccess < least {
+		return least
+	}
+	return c.stopRefreshAfterLastAccess
+}
+
+func (c *jetCache) nextRefreshDelay(now time.Time) time.Duration {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	if len(c.refreshQueue) == 0 {
+		return maxRefreshIdle
+	}
+	return c.refreshQueue[0].nextRefreshTime.Sub(now)
+}
+
+// startRefresh starts the scheduler unless the refresh is stopped. The check
+// and the Add hold timerMu, as afterFunc does, so that once stopRefresh has
+// returned the scheduler either never starts or is counted by refreshWg.
+func (c *jetCache) startRefresh() {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	c.refreshWg.Add(1)
+	go util.WithRecover(func() {
+		defer c.refreshWg.Done()
+
+		c.runRefresh()
+	})
+}
+
+// runRefresh runs the due refresh tasks with at most refreshConcurrency loads in
+// flight, then sleeps until the earliest queued task is due.
+func (c *jetCache) runRefresh() {
+	sem := semaphore.NewWeighted(int64(c.refreshConcurrency))
+	for {
+		now := time.Now()
+		for _, task := range c.dueTasks(now) {
+			if c.refreshPaused.Load() {
+				c.schedule(task, now)
+				continue
+			}
+
+			// Acquire only fails once the cache is closed.
+			if err := sem.Acquire(c.ctx, 1); err != nil {
+				return
+			}
+
+			task := task
+			c.refreshWg.Add(1)
+			go util.WithRecover(func() {
+				defer c.refreshWg.Done()
+				defer sem.Release(1)
+
+				c.refresh(task, now)
+			})
+		}
+
+		timer := time.NewTimer(c.nextRefreshDelay(now))
+		select {
+		case <-timer.C:
+		case <-c.refreshWakeCh:
+			timer.Stop()
+		case <-c.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// refresh loads the task and schedules its next run once the load has finished,
+// so that a slow loader never overlaps with itself.
+func (c *jetCache) refresh(task *refreshTask, now time.Time) {
+	logger.Debug("start refresh key: %s", task.key)
+
+	var err error
+	if c.remote != nil {
+		err = c.externalLoad(c.ctx, task, now)
+	} else {
+		err = c.load(c.ctx, task)
+	}
+
+	task.done(now, err)
+	c.schedule(task, time.Now())
+}
+
+// afterFunc calls fn after duration d unless the refresh is stopped first.
+func (c *jetCache) afterFunc(d time.Duration, fn func()) {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	c.refreshWg.Add(1)
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		defer c.refreshWg.Done()
+
+		c.timerMu.Lock()
+		delete(c.timers, t)
+		c.timerMu.Unlock()
+
+		util.WithRecover(fn)
+	})
+	c.timers[t] = struct{}{}
+}
+
+func (c *jetCache) stopTimers() {
+	c.timerMu.Lock()
+	defer c.timerMu.Unlock()
+
+	for t := range c.timers {
+		if t.Stop() {
+			c.refreshWg.Done()
+		}
+		delete(c.timers, t)
+	}
+}
+
+func (c *jetCache) externalLoad(ctx context.Context, task *refreshTask, now time.Time) error {
+	var (
+		lockKey    = fmt.Sprintf("%s%s", task.key, lockKeySuffix)
+		shouldLoad bool
+	)
+	_, err := c.remote.Get(ctx, lockKey)
+	if errors.Is(err, c.remote.Nil()) {
+		shouldLoad = true
+	} else if err != nil {
+		logger.Error("externalLoad#c.remote.Get(%s) error(%v)", lockKey, err)
+		return err
+	}
+
+	if !shouldLoad {
+		if c.local != nil {
+			return c.refreshLocal(ctx, task)
+		}
+		return nil
+	}
+
+	// issues: https://github.com/mgtv-tech/jetcache-go/issues/36
+	lockTimeout := task.refreshDuration - 10*time.Millisecond
+	ok, err := c.remote.SetNX(ctx, lockKey, strconv.FormatInt(now.Unix(), 10), lockTimeout)
+	if err != nil {
+		logger.Error("externalLoad#c.remote.setNX(%s) error(%v)", lockKey, err)
+		return err
+	}
+	if ok {
+		return c.load(ctx, task)
+	} else if c.local != nil {
+		// If this goroutine fails to acquire the concurrent lock, it needs to wait briefly (delay) to trigger a refresh.
+		// This way, it can directly fetch the origin result from Redis and refresh it locally.
+		// The maximum concurrency here refers to the number of web machine instances, and the probability of
+		// concurrent processing is actually not high. time.AfterFunc can be understood as a fallback mechanism to
+		// reduce cache inconsistency time.
+		c.afterFunc(task.refreshDuration/5, func() {
+			_ = c.refreshLocal(c.ctx, task)
+		})
+	}
+
+	return nil
+}
+
+func (c *jetCache) load(ctx context.Context, task *refreshTask) error {
+	_, ok, err := c.set(newItemOptions(ctx, task.key, TTL(task.ttl), Do(task.do), SetXX(task.setXX),
+		SetNX(task.setNX), SkipLocal(task.skipLocal), NotFoundTTL(task.notFoundTTL)))
+	if ok {
+		c.send(EventTypeSetByRefresh, task.key)
+	}
+	if err != nil {
+		logger.Error("load#c.Set(%s) error(%v)", task.key, err)
+	}
+
+	return err
+}
+
+func (c *jetCache) refreshLocal(ctx context.Context, task *refreshTask) error {
+	val, err := c.remote.Get(ctx, task.key)
+	if err != nil {
+		logger.Error("refreshLocal#c.remote.Get(%s) error(%v)", task.key, err)
+		return err
+	}
+	b, ok := c.verify(ctx, task.key, util.Bytes(val))
+	if !ok {
+		c.delLocalTiers(task.key)
+		c.local.Del(task.key)
+		return ErrCacheMiss
+	}
+	c.delLocalTiers(task.key)
+	c.local.Set(task.key, b)
+
+	return nil
+}
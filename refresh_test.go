@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshSchedule(t *testing.T) {
+	do := func(context.Context) (any, error) {
+		return "any", nil
+	}
+
+	t.Run("per key refresh duration", func(t *testing.T) {
+		c := New(WithLocal(localNew(freeCache)), WithRefreshDuration(time.Minute)).(*jetCache)
+		defer c.Close()
+
+		now := time.Now()
+		c.addOrUpdateRefreshTask(newItemOptions(context.TODO(), "k1", Do(do), Refresh(true)))
+		c.addOrUpdateRefreshTask(newItemOptions(context.TODO(), "k2", Do(do), Refresh(true),
+			RefreshDuration(10*time.Second)))
+		assert.Equal(t, 2, c.TaskSize())
+
+		ins, _ := c.refreshTaskMap.Load("k1")
+		k1 := ins.(*refreshTask)
+		assert.Equal(t, time.Minute, k1.refreshDuration)
+		assert.False(t, k1.nextRefreshTime.Before(now.Add(54*time.Second)))
+		assert.False(t, k1.nextRefreshTime.After(time.Now().Add(time.Minute)))
+
+		ins, _ = c.refreshTaskMap.Load("k2")
+		k2 := ins.(*refreshTask)
+		assert.Equal(t, 10*time.Second, k2.refreshDuration)
+		assert.Equal(t, 0, k2.index)
+		assert.Equal(t, 1, k1.index)
+	})
+
+	t.Run("refresh disabled without duration", func(t *testing.T) {
+		c := New(WithLocal(localNew(freeCache))).(*jetCache)
+		defer c.Close()
+
+		c.addOrUpdateRefreshTask(newItemOptions(context.TODO(), "k1", Do(do), Refresh(true)))
+		assert.Equal(t, 0, c.TaskSize())
+
+		c.addOrUpdateRefreshTask(newItemOptions(context.TODO(), "k1", Do(do), Refresh(true),
+			RefreshDuration(time.Minute)))
+		assert.Equal(t, 1, c.TaskSize())
+	})
+
+	t.Run("due tasks", func(t *testing.T) {
+		c := New(WithLocal(localNew(freeCache)), WithRefreshDuration(time.Minute),
+			WithStopRefreshAfterLastAccess(time.Minute)).(*jetCache)
+		defer c.Close()
+
+		for _, key := range []string{"k1", "k2", "k3"} {
+			c.addOrUpdateRefreshTask(newItemOptions(context.TODO(), key, Do(do), Refresh(true)))
+		}
+		ins, _ := c.refreshTaskMap.Load("k3")
+		ins.(*refreshTask).lastAccessTime = time.Now().Add(-time.Hour)
+
+		assert.Empty(t, c.dueTasks(time.Now()))
+
+		due := c.dueTasks(time.Now().Add(time.Minute))
+		assert.Len(t, due, 2)
+		for _, task := range due {
+			assert.Equal(t, -1, task.index)
+		}
+		assert.Equal(t, 2, c.TaskSize())
+		assert.Equal(t, maxRefreshIdle, c.nextRefreshDelay(time.Now()))
+
+		c.schedule(due[0], time.Now())
+		assert.Equal(t, 0, due[0].index)
+		c.cancel(due[0].key)
+		assert.Equal(t, -1, due[0].index)
+		assert.Equal(t, 1, c.TaskSize())
+	})
+
+	t.Run("stop refresh after", func(t *testing.T) {
+		c := New(WithLocal(localNew(freeCache)), WithRefreshDuration(time.Second),
+			WithStopRefreshAfterLastAccess(3*time.Second)).(*jetCache)
+		defer c.Close()
+
+		assert.Equal(t, 3*time.Second, c.stopRefreshAfter(&refreshTask{refreshDuration: time.Second}))
+		assert.Equal(t, 11*time.Second, c.stopRefreshAfter(&refreshTask{refreshDuration: 10 * time.Second}))
+	})
+}
+
+func TestRefreshControl(t *testing.T) {
+	var calls int
+	c := New(WithLocal(localNew(freeCache)), WithRefreshDuration(time.Minute)).(*jetCache)
+	defer c.Close()
+
+	var value int
+	err := c.Once(context.TODO(), "k1", Value(&value), Refresh(true), Do(func(context.Context) (any, error) {
+		calls++
+		return calls, nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, value)
+
+	t.Run("refresh tasks", func(t *testing.T) {
+		tasks := c.RefreshTasks()
+		assert.Len(t, tasks, 1)
+		assert.Equal(t, "k1", tasks[0].Key)
+		assert.Equal(t, time.Minute, tasks[0].RefreshDuration)
+		assert.False(t, tasks[0].LastAccessTime.IsZero())
+		assert.True(t, tasks[0].LastRefreshTime.IsZero())
+		assert.True(t, tasks[0].NextRefreshTime.After(time.Now()))
+	})
+
+	t.Run("refresh now", func(t *testing.T) {
+		assert.Equal(t, ErrRefreshTaskNotFound, c.RefreshNow(context.TODO(), "k2"))
+
+		c.PauseRefresh()
+		assert.NoError(t, c.RefreshNow(context.TODO(), "k1"))
+		c.ResumeRefresh()
+		assert.NoError(t, c.Get(context.TODO(), "k1", &value))
+		assert.Equal(t, 2, value)
+
+		tasks := c.RefreshTasks()
+		assert.False(t, tasks[0].LastRefreshTime.IsZero())
+		assert.NoError(t, tasks[0].LastErr)
+		assert.True(t, tasks[0].NextRefreshTime.After(time.Now()))
+	})
+
+	t.Run("paused tasks are rescheduled", func(t *testing.T) {
+		c.PauseRefresh()
+		defer c.ResumeRefresh()
+
+		ins, _ := c.refreshTaskMap.Load("k1")
+		task := ins.(*refreshTask)
+		c.unschedule(task)
+		c.schedule(task, time.Now().Add(-time.Minute))
+		assert.Eventually(t, func() bool {
+			return c.RefreshTasks()[0].NextRefreshTime.After(time.Now())
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("cancel refresh", func(t *testing.T) {
+		c.CancelRefresh("k1")
+		assert.Empty(t, c.RefreshTasks())
+	})
+}
+
+func TestRefreshNowConcurrent(t *testing.T) {
+	c := New(WithLocal(localNew(freeCache)), WithRefreshDuration(time.Minute)).(*jetCache)
+	defer c.Close()
+
+	var calls int32
+	err := c.Once(context.TODO(), "k1", Refresh(true), Do(func(context.Context) (any, error) {
+		time.Sleep(10 * time.Millisecond) // so that the refreshes overlap.
+		return atomic.AddInt32(&calls, 1), nil
+	}))
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.RefreshNow(context.TODO(), "k1"))
+		}()
+	}
+	wg.Wait()
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	assert.Len(t, c.refreshQueue, 1)
+	assert.Equal(t, 0, c.refreshQueue[0].index)
+	assert.Equal(t, int32(21), atomic.LoadInt32(&calls))
+}
+
+func TestShutdownConcurrentStartRefresh(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		c := New(WithLocal(localNew(freeCache)), WithRefreshDuration(time.Minute)).(*jetCache)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.addOrUpdateRefreshTask(newItemOptions(context.TODO(), "k1", Do(func(context.Context) (any, error) {
+				return "any", nil
+			}), Refresh(true)))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.Shutdown(context.TODO()))
+		}()
+		wg.Wait()
+
+		// the scheduler either never started or was waited for.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		assert.NoError(t, wait(ctx, &c.refreshWg))
+		cancel()
+	}
+}
@@ -0,0 +1,311 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go/encoding"
+)
+
+const maxKeyIDLen = 255
+
+var (
+	// ErrNotEncrypted is returned when a value read is not encrypted, unless
+	// WithPlaintextFallback is set.
+	ErrNotEncrypted = errors.New("remote: value is not encrypted")
+	// ErrUnknownKey is returned when a value is encrypted with an unknown key ID.
+	ErrUnknownKey = errors.New("remote: unknown encryption key")
+	// ErrDecrypt is returned when a value fails to decrypt or authenticate.
+	ErrDecrypt = errors.New("remote: decrypt error")
+	// ErrEncryptedCounter is returned by EncryptedRemote.IncrBy, as the counters
+	// are stored as plain integers by the Remotes.
+	ErrEncryptedCounter = errors.New("remote: counters are not available under encryption")
+	// ErrSwapUnsupported is returned by EncryptedRemote.CompareAndSwap when the
+	// wrapped Remote does not implement Swapper.
+	ErrSwapUnsupported = errors.New("remote: wrapped remote does not implement remote.Swapper")
+)
+
+var (
+	_ Remote  = (*EncryptedRemote)(nil)
+	_ Counter = (*EncryptedRemote)(nil)
+	_ Swapper = (*EncryptedRemote)(nil)
+)
+
+type (
+	// Key is an AES key of 16, 24 or 32 bytes, tagged by its ID in the values
+	// it encrypts.
+	Key struct {
+		ID     string
+		Secret []byte
+	}
+
+	// EncryptOption defines the method to customize an EncryptedRemote.
+	EncryptOption func(r *EncryptedRemote) error
+
+	// EncryptedRemote is a Remote decorator encrypting the values with AES-GCM.
+	// Values are written with the current key and read with any known key, so
+	// keys rotate by making the new key current and keeping the old ones for
+	// decryption until the values they encrypted expire.
+	//
+	// Whole values are encrypted, including the not found placeholder and the
+	// msgpack compression flag byte, so they are read back unchanged. The key
+	// of a value is authenticated with it, so a value copied to another key
+	// fails to decrypt.
+	//
+	// Counters are not available, see ErrEncryptedCounter. CompareAndSwap is
+	// forwarded when the wrapped Remote implements Swapper.
+	EncryptedRemote struct {
+		Remote
+		current   string
+		aeads     map[string]cipher.AEAD
+		plaintext bool
+	}
+)
+
+// WithDecryptKeys adds old keys to decrypt the values written before a rotation.
+func WithDecryptKeys(keys ...Key) EncryptOption {
+	return func(r *EncryptedRemote) error {
+		for _, key := range keys {
+			if err := r.addKey(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// WithPlaintextFallback returns the values which are not encrypted as is,
+// instead of failing with ErrNotEncrypted, to read the values written before
+// encryption was enabled.
+func WithPlaintextFallback(plaintext bool) EncryptOption {
+	return func(r *EncryptedRemote) error {
+		r.plaintext = plaintext
+		return nil
+	}
+}
+
+// NewEncryptedRemote returns a Remote encrypting the values of r with the current key.
+func NewEncryptedRemote(r Remote, current Key, opts ...EncryptOption) (*EncryptedRemote, error) {
+	e := &EncryptedRemote{
+		Remote:  r,
+		current: current.ID,
+		aeads:   make(map[string]cipher.AEAD),
+	}
+	if err := e.addKey(current); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+func (r *EncryptedRemote) SetEX(ctx context.Context, key string, value any, expire time.Duration) error {
+	b, err := r.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+
+	return r.Remote.SetEX(ctx, key, b, expire)
+}
+
+func (r *EncryptedRemote) SetNX(ctx context.Context, key string, value any, expire time.Duration) (bool, error) {
+	b, err := r.encrypt(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	return r.Remote.SetNX(ctx, key, b, expire)
+}
+
+func (r *EncryptedRemote) SetXX(ctx context.Context, key string, value any, expire time.Duration) (bool, error) {
+	b, err := r.encrypt(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	return r.Remote.SetXX(ctx, key, b, expire)
+}
+
+func (r *EncryptedRemote) Get(ctx context.Context, key string) (string, error) {
+	val, err := r.Remote.Get(ctx, key)
+	if err != nil {
+		return val, err
+	}
+
+	b, err := r.decrypt(key, val)
+	if err != nil {
+		return "", fmt.Errorf("EncryptedRemote#Get(%s) error(%w)", key, err)
+	}
+
+	return string(b), nil
+}
+
+// MGet drops the values failing to decrypt, which are then reloaded and
+// written again with the current key.
+func (r *EncryptedRemote) MGet(ctx context.Context, keys ...string) (map[string]any, error) {
+	values, err := r.Remote.MGet(ctx, keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]any, len(values))
+	for key, val := range values {
+		s, ok := val.(string)
+		if !ok {
+			continue
+		}
+		if b, err := r.decrypt(key, s); err == nil {
+			ret[key] = string(b)
+		}
+	}
+
+	return ret, nil
+}
+
+func (r *EncryptedRemote) MSet(ctx context.Context, value map[string]any, expire time.Duration) error {
+	encrypted := make(map[string]any, len(value))
+	for key, val := range value {
+		b, err := r.encrypt(key, val)
+		if err != nil {
+			return err
+		}
+		encrypted[key] = b
+	}
+
+	return r.Remote.MSet(ctx, encrypted, expire)
+}
+
+// IncrBy always fails with ErrEncryptedCounter.
+func (r *EncryptedRemote) IncrBy(context.Context, string, int64, time.Duration) (int64, error) {
+	return 0, ErrEncryptedCounter
+}
+
+// CompareAndSwap compares old with the decrypted value of the key, and swaps
+// the encrypted value if the stored ciphertext is unchanged since it was read.
+func (r *EncryptedRemote) CompareAndSwap(ctx context.Context, key string, old []byte, exists bool, value []byte, expire time.Duration) (bool, error) {
+	swapper, ok := r.Remote.(Swapper)
+	if !ok {
+		return false, ErrSwapUnsupported
+	}
+
+	var raw []byte
+	if exists {
+		val, err := r.Remote.Get(ctx, key)
+		if errors.Is(err, r.Remote.Nil()) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		b, err := r.decrypt(key, val)
+		if err != nil {
+			return false, fmt.Errorf("EncryptedRemote#CompareAndSwap(%s) error(%w)", key, err)
+		}
+		if !bytes.Equal(b, old) {
+			return false, nil
+		}
+		raw = []byte(val)
+	}
+
+	b, err := r.encrypt(key, value)
+	if err != nil {
+		return false, err
+	}
+
+	return swapper.CompareAndSwap(ctx, key, raw, exists, b, expire)
+}
+
+func (r *EncryptedRemote) addKey(key Key) error {
+	if key.ID == "" || len(key.ID) > maxKeyIDLen {
+		return fmt.Errorf("remote: invalid encryption key ID %q", key.ID)
+	}
+	if _, ok := r.aeads[key.ID]; ok {
+		return fmt.Errorf("remote: duplicate encryption key ID %q", key.ID)
+	}
+
+	block, err := aes.NewCipher(key.Secret)
+	if err != nil {
+		return fmt.Errorf("remote: encryption key %q: %w", key.ID, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	r.aeads[key.ID] = aead
+
+	return nil
+}
+
+// encrypt returns the header, the key ID, the nonce and the sealed value.
+func (r *EncryptedRemote) encrypt(key string, value any) ([]byte, error) {
+	var plaintext []byte
+	switch v := value.(type) {
+	case []byte:
+		plaintext = v
+	case string:
+		plaintext = []byte(v)
+	default:
+		return nil, fmt.Errorf("remote: cannot encrypt value of type %T", value)
+	}
+
+	aead := r.aeads[r.current]
+	headerLen := encoding.HeaderLen + 1 + len(r.current)
+	b := make([]byte, 0, headerLen+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	b = encoding.AppendHeader(b, encoding.FormatEncrypted)
+	b = append(b, byte(len(r.current)))
+	b = append(b, r.current...)
+	b = b[:headerLen+aead.NonceSize()]
+	nonce := b[headerLen:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(b, nonce, plaintext, additionalData(b[:headerLen], key)), nil
+}
+
+func (r *EncryptedRemote) decrypt(key, val string) ([]byte, error) {
+	b := []byte(val)
+	if len(b) <= encoding.HeaderLen || !encoding.HasHeader(b, encoding.FormatEncrypted) {
+		if r.plaintext {
+			return b, nil
+		}
+		return nil, ErrNotEncrypted
+	}
+
+	headerLen := encoding.HeaderLen + 1 + int(b[encoding.HeaderLen])
+	if len(b) < headerLen {
+		return nil, ErrDecrypt
+	}
+	id := string(b[encoding.HeaderLen+1 : headerLen])
+	aead, ok := r.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	if len(b) < headerLen+aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+
+	nonce := b[headerLen : headerLen+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, b[headerLen+aead.NonceSize():], additionalData(b[:headerLen], key))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plaintext, nil
+}
+
+// additionalData authenticates the header with the key of the value, so that
+// neither the key ID nor the key can be swapped.
+func additionalData(header []byte, key string) []byte {
+	return append(header[:len(header):len(header)], key...)
+}
@@ -0,0 +1,173 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	key1 = Key{ID: "k1", Secret: bytes.Repeat([]byte{1}, 32)}
+	key2 = Key{ID: "k2", Secret: bytes.Repeat([]byte{2}, 16)}
+)
+
+func TestEncryptedRemote(t *testing.T) {
+	ctx := context.Background()
+	plain := NewGoRedisV9Adapter(newRdb())
+	client, err := NewEncryptedRemote(plain, key1)
+	assert.NoError(t, err)
+
+	// The msgpack flag byte and the placeholder are read back unchanged.
+	values := map[string]any{"key1": []byte{'v', 0x1}, "key2": "*"}
+	assert.NoError(t, client.MSet(ctx, values, time.Minute))
+	assert.NoError(t, client.SetEX(ctx, "key3", "value3", time.Minute))
+
+	raw, err := plain.Get(ctx, "key3")
+	assert.NoError(t, err)
+	assert.NotContains(t, raw, "value3")
+	assert.Equal(t, "k1", raw[5:7])
+
+	val, err := client.Get(ctx, "key3")
+	assert.NoError(t, err)
+	assert.Equal(t, "value3", val)
+
+	result, err := client.MGet(ctx, "key1", "key2", "key3", "key4")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"key1": "v\x01", "key2": "*", "key3": "value3"}, result)
+
+	ok, err := client.SetNX(ctx, "key3", "other", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = client.SetXX(ctx, "key3", "value4", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	val, err = client.Get(ctx, "key3")
+	assert.NoError(t, err)
+	assert.Equal(t, "value4", val)
+
+	_, err = client.Get(ctx, "key4")
+	assert.ErrorIs(t, err, client.Nil())
+}
+
+func TestEncryptedRemoteRotation(t *testing.T) {
+	ctx := context.Background()
+	plain := NewGoRedisV9Adapter(newRdb())
+	old, err := NewEncryptedRemote(plain, key1)
+	assert.NoError(t, err)
+	assert.NoError(t, old.SetEX(ctx, "key1", "value1", time.Minute))
+
+	client, err := NewEncryptedRemote(plain, key2, WithDecryptKeys(key1))
+	assert.NoError(t, err)
+	val, err := client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value1", val)
+
+	// Writes re-encrypt with the current key.
+	assert.NoError(t, client.SetEX(ctx, "key1", val, time.Minute))
+	raw, err := plain.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "k2", raw[5:7])
+
+	_, err = old.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	result, err := old.MGet(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestEncryptedRemotePlaintext(t *testing.T) {
+	ctx := context.Background()
+	plain := NewGoRedisV9Adapter(newRdb())
+	assert.NoError(t, plain.SetEX(ctx, "key1", "value1", time.Minute))
+
+	client, err := NewEncryptedRemote(plain, key1)
+	assert.NoError(t, err)
+	_, err = client.Get(ctx, "key1")
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+
+	client, err = NewEncryptedRemote(plain, key1, WithPlaintextFallback(true))
+	assert.NoError(t, err)
+	val, err := client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value1", val)
+}
+
+func TestEncryptedRemoteError(t *testing.T) {
+	ctx := context.Background()
+	plain := NewGoRedisV9Adapter(newRdb())
+
+	_, err := NewEncryptedRemote(plain, Key{Secret: key1.Secret})
+	assert.Error(t, err)
+	_, err = NewEncryptedRemote(plain, Key{ID: "k1", Secret: []byte("short")})
+	assert.Error(t, err)
+	_, err = NewEncryptedRemote(plain, key1, WithDecryptKeys(key1))
+	assert.Error(t, err)
+
+	client, err := NewEncryptedRemote(plain, key1)
+	assert.NoError(t, err)
+	assert.Error(t, client.SetEX(ctx, "key1", 1, time.Minute))
+	_, err = client.SetNX(ctx, "key1", 1, time.Minute)
+	assert.Error(t, err)
+	_, err = client.SetXX(ctx, "key1", 1, time.Minute)
+	assert.Error(t, err)
+	assert.Error(t, client.MSet(ctx, map[string]any{"key1": 1}, time.Minute))
+
+	assert.NoError(t, client.SetEX(ctx, "key1", "value1", time.Minute))
+	raw, err := plain.Get(ctx, "key1")
+	assert.NoError(t, err)
+	tampered := []byte(raw)
+	tampered[len(tampered)-1] ^= 0xff
+	for _, v := range []string{string(tampered), raw[:5], raw[:10]} {
+		assert.NoError(t, plain.SetEX(ctx, "key1", v, time.Minute))
+		_, err = client.Get(ctx, "key1")
+		assert.ErrorIs(t, err, ErrDecrypt)
+	}
+
+	// A value copied to another key fails to authenticate.
+	assert.NoError(t, client.SetEX(ctx, "key1", "value1", time.Minute))
+	raw, err = plain.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.NoError(t, plain.SetEX(ctx, "key2", raw, time.Minute))
+	_, err = client.Get(ctx, "key2")
+	assert.ErrorIs(t, err, ErrDecrypt)
+	result, err := client.MGet(ctx, "key1", "key2")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"key1": "value1"}, result)
+}
+
+func TestEncryptedRemoteCapabilities(t *testing.T) {
+	ctx := context.Background()
+	plain := NewGoRedisV9Adapter(newRdb())
+	client, err := NewEncryptedRemote(plain, key1)
+	assert.NoError(t, err)
+
+	_, err = client.IncrBy(ctx, "counter", 1, time.Minute)
+	assert.ErrorIs(t, err, ErrEncryptedCounter)
+
+	swapped, err := client.CompareAndSwap(ctx, "key1", nil, false, []byte("value1"), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+	swapped, err = client.CompareAndSwap(ctx, "key1", nil, false, []byte("other"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+	swapped, err = client.CompareAndSwap(ctx, "key1", []byte("other"), true, []byte("value2"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+	swapped, err = client.CompareAndSwap(ctx, "key1", []byte("value1"), true, []byte("value2"), time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, swapped)
+	val, err := client.Get(ctx, "key1")
+	assert.NoError(t, err)
+	assert.Equal(t, "value2", val)
+	swapped, err = client.CompareAndSwap(ctx, "key2", []byte("value1"), true, []byte("value2"), time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, swapped)
+
+	noSwap, err := NewEncryptedRemote(struct{ Remote }{plain}, key1)
+	assert.NoError(t, err)
+	_, err = noSwap.CompareAndSwap(ctx, "key1", nil, false, []byte("value1"), time.Minute)
+	assert.ErrorIs(t, err, ErrSwapUnsupported)
+}
@@ -8,7 +8,41 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-var _ Remote = (*GoRedisV9Adapter)(nil)
+var (
+	_ Remote  = (*GoRedisV9Adapter)(nil)
+	_ Counter = (*GoRedisV9Adapter)(nil)
+	_ Swapper = (*GoRedisV9Adapter)(nil)
+)
+
+// incrByScript increments the key and sets the expiration only when the key
+// is created, so that the counter and its TTL are written atomically.
+var incrByScript = redis.NewScript(`
+local created = redis.call('EXISTS', KEYS[1]) == 0
+local val = redis.call('INCRBY', KEYS[1], ARGV[1])
+if created and tonumber(ARGV[2]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return val
+`)
+
+// compareAndSwapScript sets the key if its value is ARGV[2], or if it is
+// missing when ARGV[1] is 0, so that concurrent updates cannot be lost.
+var compareAndSwapScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if ARGV[1] == '1' then
+	if cur ~= ARGV[2] then
+		return 0
+	end
+elseif cur then
+	return 0
+end
+if tonumber(ARGV[4]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[3], 'PX', ARGV[4])
+else
+	redis.call('SET', KEYS[1], ARGV[3])
+end
+return 1
+`)
 
 type GoRedisV9Adapter struct {
 	client redis.Cmdable
@@ -41,6 +75,20 @@ func (r *GoRedisV9Adapter) Del(ctx context.Context, key string) (val int64, err
 	return r.client.Del(ctx, key).Result()
 }
 
+func (r *GoRedisV9Adapter) IncrBy(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error) {
+	return incrByScript.Run(ctx, r.client, []string{key}, delta, expire.Milliseconds()).Int64()
+}
+
+func (r *GoRedisV9Adapter) CompareAndSwap(ctx context.Context, key string, old []byte, exists bool, value []byte, expire time.Duration) (bool, error) {
+	flag := 0
+	if exists {
+		flag = 1
+	}
+	swapped, err := compareAndSwapScript.Run(ctx, r.client, []string{key}, flag, old, value, expire.Milliseconds()).Int()
+
+	return swapped == 1, err
+}
+
 func (r *GoRedisV9Adapter) MGet(ctx context.Context, keys ...string) (map[string]any, error) {
 	pipeline := r.client.Pipeline()
 	keyIdxMap := make(map[int]string, len(keys))
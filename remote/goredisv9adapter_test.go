@@ -78,6 +78,74 @@ func TestGoRedisV9Adaptor_SetXxNx(t *testing.T) {
 	assert.Equal(t, "value1", val)
 }
 
+func TestGoRedisV9Adaptor_IncrBy(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRdb()
+	client := NewGoRedisV9Adapter(rdb).(Counter)
+
+	val, err := client.IncrBy(ctx, "counter", 2, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), val)
+	assert.Equal(t, time.Minute, rdb.TTL(ctx, "counter").Val())
+
+	// the expiration is set on create only.
+	assert.Nil(t, rdb.Expire(ctx, "counter", time.Hour).Err())
+	val, err = client.IncrBy(ctx, "counter", -5, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-3), val)
+	assert.Equal(t, time.Hour, rdb.TTL(ctx, "counter").Val())
+
+	val, err = client.IncrBy(ctx, "forever", 1, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), val)
+	assert.Equal(t, time.Duration(-1), rdb.TTL(ctx, "forever").Val())
+
+	assert.Nil(t, rdb.Set(ctx, "text", "value", time.Minute).Err())
+	_, err = client.IncrBy(ctx, "text", 1, time.Minute)
+	assert.NotNil(t, err)
+}
+
+func TestGoRedisV9Adaptor_CompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	rdb := newRdb()
+	client := NewGoRedisV9Adapter(rdb).(Swapper)
+
+	swapped, err := client.CompareAndSwap(ctx, "key1", nil, false, []byte("value1"), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, swapped)
+	assert.Equal(t, time.Minute, rdb.TTL(ctx, "key1").Val())
+
+	// the key exists.
+	swapped, err = client.CompareAndSwap(ctx, "key1", nil, false, []byte("value2"), time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+
+	swapped, err = client.CompareAndSwap(ctx, "key1", []byte("stale"), true, []byte("value2"), time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+	assert.Equal(t, "value1", rdb.Get(ctx, "key1").Val())
+
+	swapped, err = client.CompareAndSwap(ctx, "key1", []byte("value1"), true, []byte("value2"), 0)
+	assert.Nil(t, err)
+	assert.True(t, swapped)
+	assert.Equal(t, "value2", rdb.Get(ctx, "key1").Val())
+	assert.Equal(t, time.Duration(-1), rdb.TTL(ctx, "key1").Val())
+
+	// the key is missing.
+	swapped, err = client.CompareAndSwap(ctx, "key2", []byte("value1"), true, []byte("value2"), time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+
+	// an empty value exists.
+	assert.Nil(t, rdb.Set(ctx, "key3", "", time.Minute).Err())
+	swapped, err = client.CompareAndSwap(ctx, "key3", nil, false, []byte("value1"), time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, swapped)
+	swapped, err = client.CompareAndSwap(ctx, "key3", nil, true, []byte("value1"), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, swapped)
+}
+
 func newRdb() *redis.Client {
 	s, err := miniredis.Run()
 	if err != nil {
@@ -30,3 +30,21 @@ type Remote interface {
 	// Nil returns an error indicating that the key does not exist.
 	Nil() error
 }
+
+// Counter is implemented by the Remotes supporting atomic counters, see
+// cache.Cache.Incr. It is optional so that existing Remotes keep compiling.
+type Counter interface {
+	// IncrBy atomically adds delta to the integer value of a key and returns
+	// the new value. A missing key is created with the value delta, expiring
+	// after expire if it is positive. The expiration of existing keys is kept.
+	IncrBy(ctx context.Context, key string, delta int64, expire time.Duration) (int64, error)
+}
+
+// Swapper is implemented by the Remotes supporting compare-and-swap, see
+// cache.T.Update. It is optional so that existing Remotes keep compiling.
+type Swapper interface {
+	// CompareAndSwap sets the value of a key, expiring after expire if it is
+	// positive, if the key exists with the value old, or if it is missing when
+	// exists is false. It reports whether the value was set.
+	CompareAndSwap(ctx context.Context, key string, old []byte, exists bool, value []byte, expire time.Duration) (bool, error)
+}
@@ -15,6 +15,31 @@ type (
 		IncrQueryFail(err error)
 	}
 
+	// LocalMetricsHandler is an optional extension of Handler to collect the memory
+	// and eviction metrics of the local cache, which are gauges rather than counters.
+	LocalMetricsHandler interface {
+		// SetLocalMetrics sets the function returning the current local cache metrics.
+		SetLocalMetrics(fn func() LocalMetrics)
+	}
+
+	// CorruptHandler is an optional extension of Handler to count the remote values
+	// failing the integrity check, see cache.WithChecksum.
+	CorruptHandler interface {
+		IncrCorrupt()
+	}
+
+	// LocalMetrics is a snapshot of the memory and eviction metrics of a local cache.
+	// Evictions, Expirations, Overwrites and Rejections are cumulative.
+	LocalMetrics struct {
+		Entries     int64
+		Bytes       int64
+		Capacity    int64
+		Evictions   int64
+		Expirations int64
+		Overwrites  int64
+		Rejections  int64
+	}
+
 	Handlers struct {
 		disable  bool
 		handlers []Handler
@@ -108,3 +133,29 @@ func (hs *Handlers) IncrQueryFail(err error) {
 		h.IncrQueryFail(err)
 	}
 }
+
+// SetLocalMetrics sets the function on the handlers implementing LocalMetricsHandler.
+func (hs *Handlers) SetLocalMetrics(fn func() LocalMetrics) {
+	if hs.disable {
+		return
+	}
+
+	for _, h := range hs.handlers {
+		if lh, ok := h.(LocalMetricsHandler); ok {
+			lh.SetLocalMetrics(fn)
+		}
+	}
+}
+
+// IncrCorrupt calls IncrCorrupt on the handlers implementing CorruptHandler.
+func (hs *Handlers) IncrCorrupt() {
+	if hs.disable {
+		return
+	}
+
+	for _, h := range hs.handlers {
+		if ch, ok := h.(CorruptHandler); ok {
+			ch.IncrCorrupt()
+		}
+	}
+}
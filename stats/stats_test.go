@@ -56,6 +56,28 @@ func TestNewHandles(t *testing.T) {
 	}
 }
 
+func TestHandlers_SetLocalMetrics(t *testing.T) {
+	fn := func() LocalMetrics {
+		return LocalMetrics{Entries: 1}
+	}
+
+	stat := &Stats{}
+	NewHandles(true, stat).(*Handlers).SetLocalMetrics(fn)
+	assert.Equal(t, LocalMetrics{}, stat.Snapshot().Local)
+
+	NewHandles(false, &testHandler{}, stat).(*Handlers).SetLocalMetrics(fn)
+	assert.Equal(t, int64(1), stat.Snapshot().Local.Entries)
+}
+
+func TestHandlers_IncrCorrupt(t *testing.T) {
+	stat := &Stats{}
+	NewHandles(true, stat).(*Handlers).IncrCorrupt()
+	assert.Equal(t, uint64(0), stat.Snapshot().Corrupt)
+
+	NewHandles(false, &testHandler{}, stat).(*Handlers).IncrCorrupt()
+	assert.Equal(t, uint64(1), stat.Snapshot().Corrupt)
+}
+
 func (h *testHandler) IncrHit() {
 	atomic.AddUint64(&h.Hit, 1)
 }
@@ -32,6 +32,11 @@ type (
 		RemoteMiss uint64
 		Query      uint64
 		QueryFail  uint64
+		Corrupt    uint64       // Remote values failing the integrity check.
+		Local      LocalMetrics // Set by Snapshot only.
+
+		localMetrics atomic.Value // func() LocalMetrics
+		lastLocal    LocalMetrics // Local metrics of the last stats interval, guarded by innerStats.mu.
 	}
 
 	Options struct {
@@ -43,7 +48,8 @@ type (
 
 	innerStats struct {
 		statsInterval time.Duration
-		stats         []*Stats
+		mu            sync.Mutex
+		stats         []*Stats // guarded by mu.
 	}
 )
 
@@ -80,7 +86,9 @@ func NewStatsLogger(name string, opts ...Option) Handler {
 		Options: o,
 	}
 
+	inner.mu.Lock()
 	inner.stats = append(inner.stats, stat)
+	inner.mu.Unlock()
 
 	return stat
 }
@@ -117,6 +125,47 @@ func (s *Stats) IncrQueryFail(err error) {
 	atomic.AddUint64(&s.QueryFail, 1)
 }
 
+// IncrCorrupt implements CorruptHandler.
+func (s *Stats) IncrCorrupt() {
+	atomic.AddUint64(&s.Corrupt, 1)
+}
+
+// Snapshot returns a copy of the current counters.
+func (s *Stats) Snapshot() Stats {
+	return Stats{
+		Options:    s.Options,
+		Name:       s.Name,
+		Hit:        atomic.LoadUint64(&s.Hit),
+		Miss:       atomic.LoadUint64(&s.Miss),
+		LocalHit:   atomic.LoadUint64(&s.LocalHit),
+		LocalMiss:  atomic.LoadUint64(&s.LocalMiss),
+		RemoteHit:  atomic.LoadUint64(&s.RemoteHit),
+		RemoteMiss: atomic.LoadUint64(&s.RemoteMiss),
+		Query:      atomic.LoadUint64(&s.Query),
+		QueryFail:  atomic.LoadUint64(&s.QueryFail),
+		Corrupt:    atomic.LoadUint64(&s.Corrupt),
+		Local:      s.loadLocalMetrics(),
+	}
+}
+
+// SetLocalMetrics implements LocalMetricsHandler.
+func (s *Stats) SetLocalMetrics(fn func() LocalMetrics) {
+	s.localMetrics.Store(fn)
+}
+
+func (s *Stats) loadLocalMetrics() LocalMetrics {
+	if fn, ok := s.localMetrics.Load().(func() LocalMetrics); ok {
+		return fn()
+	}
+
+	return LocalMetrics{}
+}
+
+func (s *Stats) hasLocalMetrics() bool {
+	_, ok := s.localMetrics.Load().(func() LocalMetrics)
+	return ok
+}
+
 func (inner *innerStats) statLoop(ticker *time.Ticker) {
 	for range ticker.C {
 		inner.logStatSummary()
@@ -124,9 +173,13 @@ func (inner *innerStats) statLoop(ticker *time.Ticker) {
 }
 
 func (inner *innerStats) logStatSummary() {
-	stats := make([]Stats, len(inner.stats))
+	inner.mu.Lock()
+	registered := append([]*Stats(nil), inner.stats...)
+	inner.mu.Unlock()
+
+	stats := make([]Stats, len(registered))
 	var maxNameLen int
-	for i, s := range inner.stats {
+	for i, s := range registered {
 		stats[i] = Stats{
 			Name:       s.Name,
 			Hit:        atomic.SwapUint64(&s.Hit, 0),
@@ -137,6 +190,7 @@ func (inner *innerStats) logStatSummary() {
 			LocalMiss:  atomic.SwapUint64(&s.LocalMiss, 0),
 			Query:      atomic.SwapUint64(&s.Query, 0),
 			QueryFail:  atomic.SwapUint64(&s.QueryFail, 0),
+			Corrupt:    atomic.SwapUint64(&s.Corrupt, 0),
 		}
 		if len(s.Name) > maxNameLen {
 			maxNameLen = len(s.Name)
@@ -155,6 +209,55 @@ func (inner *innerStats) logStatSummary() {
 		sb.WriteString(formatSepLine(header))
 		logger.Info(sb.String())
 	}
+	for _, s := range stats {
+		if s.Corrupt > 0 {
+			logger.Warn("jetcache-go stats last %s: cache(%s) corrupt(%d)", inner.statsInterval, s.Name, s.Corrupt)
+		}
+	}
+
+	inner.logLocalSummary(registered, maxLenStr)
+}
+
+// logLocalSummary logs the local cache metrics, the evictions, expirations,
+// overwrites and rejections of the last stats interval.
+func (inner *innerStats) logLocalSummary(registered []*Stats, maxLenStr string) {
+	var rows strings.Builder
+	for _, s := range registered {
+		if !s.hasLocalMetrics() {
+			continue
+		}
+		m := s.loadLocalMetrics()
+		inner.mu.Lock()
+		last := s.lastLocal
+		s.lastLocal = m
+		inner.mu.Unlock()
+
+		rows.WriteString(fmt.Sprintf("%-"+maxLenStr+"s|", getName(s.Name, "local")))
+		rows.WriteString(fmt.Sprintf("%12d|", m.Entries))
+		rows.WriteString(fmt.Sprintf("%12d|", m.Bytes))
+		rows.WriteString(fmt.Sprintf("%12d|", m.Capacity))
+		rows.WriteString(fmt.Sprintf("%11s", rate(uint64(max(m.Bytes, 0)), uint64(max(m.Capacity, 0)))))
+		rows.WriteString("%%|")
+		rows.WriteString(fmt.Sprintf("%12d|", m.Evictions-last.Evictions))
+		rows.WriteString(fmt.Sprintf("%12d|", m.Expirations-last.Expirations))
+		rows.WriteString(fmt.Sprintf("%12d|", m.Overwrites-last.Overwrites))
+		rows.WriteString(fmt.Sprintf("%12d", m.Rejections-last.Rejections))
+		rows.WriteString("\n")
+	}
+	if rows.Len() == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	header := fmt.Sprintf("%-"+maxLenStr+"s|%12s|%12s|%12s|%12s|%12s|%12s|%12s|%12s\n",
+		"cache", "entries", "bytes", "capacity", "usage", "evictions", "expirations", "overwrites", "rejections")
+	sb.WriteString(fmt.Sprintf("jetcache-go local stats last %s.\n", inner.statsInterval))
+	sb.WriteString(header)
+	sb.WriteString(formatSepLine(header))
+	sb.WriteString("\n")
+	sb.WriteString(rows.String())
+	sb.WriteString(formatSepLine(header))
+	logger.Info(sb.String())
 }
 
 func formatHeader(maxLenStr string) string {
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"sync"
 	"testing"
 	"time"
 
@@ -60,10 +62,28 @@ func TestStatLogger_statLoop(t *testing.T) {
 	t.Run("stat loop query not 0", func(t *testing.T) {
 		stat := NewStatsLogger("any", WithStatsInterval(time.Millisecond))
 		stat.IncrQuery()
+		stat.(CorruptHandler).IncrCorrupt()
 		time.Sleep(10 * time.Millisecond)
 	})
 }
 
+func TestStats_Snapshot(t *testing.T) {
+	stat := &Stats{Name: "any"}
+	stat.IncrHit()
+	stat.IncrHit()
+	stat.IncrMiss()
+	stat.IncrLocalHit()
+	stat.IncrLocalMiss()
+	stat.IncrRemoteHit()
+	stat.IncrRemoteMiss()
+	stat.IncrQuery()
+	stat.IncrQueryFail(errors.New("any"))
+	stat.IncrCorrupt()
+
+	expected := Stats{Name: "any", Hit: 2, Miss: 1, LocalHit: 1, LocalMiss: 1, RemoteHit: 1, RemoteMiss: 1, Query: 1, QueryFail: 1, Corrupt: 1}
+	assert.Equal(t, expected, stat.Snapshot())
+}
+
 func TestStatLogger_logStatSummary(t *testing.T) {
 	var logBuffer = &bytes.Buffer{}
 	logger.SetDefaultLogger(&testLogger{})
@@ -93,6 +113,58 @@ cache2       |           5|     100.00%|           5|           0|          50|
 	assert.Contains(t, logBuffer.String(), expected)
 }
 
+func TestStatLogger_logLocalSummary(t *testing.T) {
+	var logBuffer = &bytes.Buffer{}
+	logger.SetDefaultLogger(&testLogger{})
+	log.SetOutput(logBuffer)
+
+	metrics := LocalMetrics{Entries: 10, Bytes: 512, Capacity: 1024, Evictions: 5, Expirations: 2, Overwrites: 1, Rejections: 4}
+	stat := &Stats{Name: "cache1", Hit: 1}
+	stat.SetLocalMetrics(func() LocalMetrics {
+		return metrics
+	})
+	inner := &innerStats{
+		stats:         []*Stats{stat, {Name: "cache2", Hit: 1}},
+		statsInterval: time.Minute,
+	}
+
+	inner.logStatSummary()
+	metrics.Evictions = 8
+	metrics.Rejections = 5
+	inner.logStatSummary()
+
+	expected := `jetcache-go local stats last 1m0s.
+cache        |     entries|       bytes|    capacity|       usage|   evictions| expirations|  overwrites|  rejections
+-------------+------------+------------+------------+------------+------------+------------+------------+------------
+cache1_local |          10|         512|        1024|      50.00%|           3|           0|           0|           1
+-------------+------------+------------+------------+------------+------------+------------+------------+------------`
+
+	assert.Contains(t, logBuffer.String(), expected)
+	assert.NotContains(t, logBuffer.String(), "cache2_local")
+}
+
+func TestStatLogger_concurrentRegister(t *testing.T) {
+	logger.SetDefaultLogger(&testLogger{})
+	log.SetOutput(io.Discard)
+
+	NewStatsLogger("register")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			stat := NewStatsLogger("register").(*Stats)
+			stat.IncrHit()
+			stat.SetLocalMetrics(func() LocalMetrics { return LocalMetrics{Capacity: 1} })
+		}()
+		go func() {
+			defer wg.Done()
+			inner.logStatSummary()
+		}()
+	}
+	wg.Wait()
+}
+
 func TestFormatHeader(t *testing.T) {
 	maxLenStr := "12"
 	expected := fmt.Sprintf("%-12s|%12s|%12s|%12s|%12s|%12s|%12s\n", "cache", "qpm", "hit_ratio", "hit", "miss", "query", "query_fail")
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+	"github.com/mgtv-tech/jetcache-go/util"
+)
+
+const (
+	defaultUpdateRetries      = 10
+	defaultUpdateRetryBackoff = 5 * time.Millisecond
+	maxUpdateBackoffShift     = 5 // the backoff stops doubling after 5 retries.
+)
+
+var (
+	ErrSwapUnsupported = errors.New("cache: remote does not implement remote.Swapper")
+	ErrUpdateConflict  = errors.New("cache: update conflicts with concurrent updates")
+)
+
+// update sets the key to the value returned by fn for its current value, nil
+// if it is missing. With a remote cache, the value is set by a compare-and-swap
+// of the remote value read, and fn is called again with the new current value
+// when a concurrent update won, up to updateRetries times after a jittered
+// exponential backoff. The local caches of the other instances are invalidated
+// by an EventTypeSet event.
+func (c *jetCache) update(ctx context.Context, key string, skipLocal bool, fn func(old []byte) ([]byte, error)) ([]byte, error) {
+	if c.remote == nil {
+		if c.local == nil {
+			return nil, ErrRemoteLocalBothNil
+		}
+		return c.updateLocal(key, fn)
+	}
+
+	swapper, ok := c.remote.(remote.Swapper)
+	if !ok {
+		return nil, ErrSwapUnsupported
+	}
+
+	for attempt := 0; attempt <= c.updateRetries; attempt++ {
+		if attempt > 0 {
+			if err := c.updateBackoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
+
+		var raw, old []byte
+		s, err := c.remote.Get(ctx, key)
+		exists := err == nil
+		if exists {
+			raw = util.Bytes(s)
+			if b, ok := c.unseal(key, raw); ok && !c.isNotFoundPlaceholder(b) {
+				old = b
+			}
+		} else if !errors.Is(err, c.remote.Nil()) {
+			return nil, fmt.Errorf("update#c.remote.Get(%s) error(%v)", key, err)
+		}
+
+		b, err := fn(old)
+		if err != nil {
+			return nil, err
+		}
+
+		swapped, err := swapper.CompareAndSwap(ctx, key, raw, exists, c.seal(key, b), c.remoteExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("update#swapper.CompareAndSwap(%s) error(%v)", key, err)
+		}
+		if !swapped {
+			continue
+		}
+
+		c.delLocalTiers(key)
+		if c.local != nil {
+			if skipLocal {
+				c.local.Del(key)
+			} else {
+				c.local.Set(key, b)
+			}
+		}
+		c.send(EventTypeSet, key)
+
+		return b, nil
+	}
+
+	return nil, ErrUpdateConflict
+}
+
+// updateBackoff sleeps before the retry of the attempt, for a random delay
+// between the half and the whole of the updateRetryBackoff doubled by each
+// retry, so that the concurrent updates spread out. It returns ctx.Err() if
+// ctx is done first.
+func (c *jetCache) updateBackoff(ctx context.Context, attempt int) error {
+	backoff := c.updateRetryBackoff << min(attempt-1, maxUpdateBackoffShift)
+	delay := backoff/2 + time.Duration(c.safeRand.Int63n(int64(backoff/2)+1))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// updateLocal is the in-memory fallback of update for the local only caches.
+func (c *jetCache) updateLocal(key string, fn func(old []byte) ([]byte, error)) ([]byte, error) {
+	c.localMu.Lock()
+	defer c.localMu.Unlock()
+
+	old, ok := c.local.Get(key)
+	if !ok || c.isNotFoundPlaceholder(old) {
+		old = nil
+	}
+
+	b, err := fn(old)
+	if err != nil {
+		return nil, err
+	}
+
+	c.delLocalTiers(key)
+	c.local.Set(key, b)
+	c.send(EventTypeSet, key)
+
+	return b, nil
+}
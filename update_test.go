@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+// conflictingRemote never swaps, as if a concurrent update always won.
+type conflictingRemote struct {
+	remote.Remote
+	swaps int
+}
+
+func (r *conflictingRemote) CompareAndSwap(context.Context, string, []byte, bool, []byte, time.Duration) (bool, error) {
+	r.swaps++
+	return false, nil
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	incr := func(old *object) (*object, error) {
+		if old == nil {
+			old = &object{Str: "str"}
+		}
+		old.Num++
+		return old, nil
+	}
+
+	t.Run("remote", func(t *testing.T) {
+		rdb := newRdb()
+		c := New(WithName("update"), WithRemote(remote.NewGoRedisV9Adapter(rdb)), WithLocal(localNew(freeCache)),
+			WithChecksum(NewCRC32Checksum()), WithSyncLocal(true))
+		defer c.Close()
+		cacheT := NewT[int, *object](c, WithValueCache[*object](1<<20, time.Minute))
+
+		v, err := cacheT.Update(ctx, "update", 1, incr)
+		assert.NoError(t, err)
+		assert.Equal(t, &object{Str: "str", Num: 1}, v)
+		e := <-c.(*jetCache).eventCh
+		assert.Equal(t, EventTypeSet, e.EventType)
+		assert.Equal(t, []string{"update:1"}, e.Keys)
+
+		_, _ = cacheT.Get(ctx, "update", 1, nil)
+		_, _ = cacheT.Get(ctx, "update", 1, nil)
+		cacheT.values.cache.Wait()
+		v, err = cacheT.Update(ctx, "update", 1, incr)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v.Num)
+		<-c.(*jetCache).eventCh
+
+		// the local cache and the value cache are in step.
+		v, err = cacheT.Get(ctx, "update", 1, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, v.Num)
+		var remoteV object
+		assert.NoError(t, c.GetSkippingLocal(ctx, "update:1", &remoteV))
+		assert.Equal(t, 2, remoteV.Num)
+
+		errUpdate := errors.New("update error")
+		_, err = cacheT.Update(ctx, "update", 1, func(*object) (*object, error) { return nil, errUpdate })
+		assert.ErrorIs(t, err, errUpdate)
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		rdb := newRdb()
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			c := New(WithName("updateConcurrent"), WithRemote(remote.NewGoRedisV9Adapter(rdb)), WithLocal(localNew(freeCache)))
+			defer c.Close()
+			cacheT := NewT[int, *object](c)
+			for j := 0; j < 20; j++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := cacheT.Update(ctx, "concurrent", 1, incr)
+					assert.NoError(t, err)
+				}()
+			}
+		}
+		wg.Wait()
+
+		c := New(WithName("updateCheck"), WithRemote(remote.NewGoRedisV9Adapter(rdb)))
+		defer c.Close()
+		var v object
+		assert.NoError(t, c.Get(ctx, "concurrent:1", &v))
+		assert.Equal(t, 40, v.Num)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		rmt := &conflictingRemote{Remote: remote.NewGoRedisV9Adapter(newRdb())}
+		c := New(WithName("updateConflict"), WithRemote(rmt), WithUpdateRetries(2), WithUpdateRetryBackoff(time.Millisecond))
+		defer c.Close()
+
+		_, err := NewT[int, *object](c).Update(ctx, "conflict", 1, incr)
+		assert.ErrorIs(t, err, ErrUpdateConflict)
+		assert.Equal(t, 3, rmt.swaps)
+
+		// the backoff is interrupted by the context.
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		slowC := New(WithName("updateConflictSlow"), WithRemote(rmt), WithUpdateRetryBackoff(time.Hour))
+		defer slowC.Close()
+		_, err = NewT[int, *object](slowC).Update(timeoutCtx, "conflict", 1, incr)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("empty value", func(t *testing.T) {
+		rdb := newRdb()
+		c := New(WithName("updateEmpty"), WithRemote(remote.NewGoRedisV9Adapter(rdb)))
+		defer c.Close()
+
+		assert.NoError(t, rdb.Set(ctx, "empty:1", "", time.Minute).Err())
+		v, err := NewT[int, string](c).Update(ctx, "empty", 1, func(old string) (string, error) {
+			return old + "value", nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "value", v)
+		assert.Equal(t, "value", rdb.Get(ctx, "empty:1").Val())
+	})
+
+	t.Run("local only", func(t *testing.T) {
+		c := New(WithName("updateLocal"), WithLocal(localNew(freeCache)))
+		defer c.Close()
+		cacheT := NewT[int, *object](c)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = cacheT.Update(ctx, "local", 1, incr)
+			}()
+		}
+		wg.Wait()
+
+		v, err := cacheT.Get(ctx, "local", 1, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 20, v.Num)
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		rmt, err := remote.NewEncryptedRemote(remote.NewGoRedisV9Adapter(newRdb()),
+			remote.Key{ID: "k1", Secret: make([]byte, 32)})
+		assert.NoError(t, err)
+		c := New(WithName("updateEncrypted"), WithRemote(rmt))
+		defer c.Close()
+		cacheT := NewT[int, *object](c)
+
+		for i := 1; i <= 2; i++ {
+			v, err := cacheT.Update(ctx, "encrypted", 1, incr)
+			assert.NoError(t, err)
+			assert.Equal(t, i, v.Num)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		c := New(WithName("updateUnsupported"), WithRemote(&failingDelRemote{Remote: remote.NewGoRedisV9Adapter(newRdb())}))
+		defer c.Close()
+
+		_, err := NewT[int, *object](c).Update(ctx, "unsupported", 1, incr)
+		assert.ErrorIs(t, err, ErrSwapUnsupported)
+	})
+}
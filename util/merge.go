@@ -23,3 +23,13 @@ func MergeMap[K comparable, V any](maps ...map[K]V) map[K]V {
 
 	return result
 }
+
+// MapValues returns the values of the map, in no particular order.
+func MapValues[K comparable, V any](m map[K]V) []V {
+	values := make([]V, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+
+	return values
+}
@@ -59,3 +59,8 @@ func TestMergeMap(t *testing.T) {
 		assert.Equal(t, expected, actual)
 	})
 }
+
+func TestMapValues(t *testing.T) {
+	assert.Empty(t, MapValues[string, int](nil))
+	assert.ElementsMatch(t, []int{1, 2}, MapValues(map[string]int{"a": 1, "b": 2}))
+}
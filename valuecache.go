@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"hash/maphash"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto/v2"
+)
+
+const (
+	valueCacheNumCounters = 1e6 // number of keys to track frequency of (1M).
+	valueCacheBufferItems = 64  // number of keys per Get buffer.
+	valueCacheStripes     = 64  // number of invalidation sequences.
+)
+
+type (
+	// localTier is a local tier kept next to the local cache, such as the
+	// decoded value tier of a T. It is invalidated whenever the key changes.
+	localTier interface {
+		Del(key string)
+		Clear()
+		DelPrefix(prefix string)
+	}
+
+	// TOption defines the method to customize a T.
+	TOption[V any] func(o *tOptions[V])
+
+	tOptions[V any] struct {
+		valueSize       int64
+		valueTTL        time.Duration
+		valueOnly       bool
+		clone           func(V) V
+		sizeOf          func(V) int64
+		batchSize       int
+		loadConcurrency int
+		loadTimeout     time.Duration
+		coalesceWindow  time.Duration
+	}
+
+	// valueTier is a local tier of decoded values, so that hits skip decoding.
+	// It is filled by reads only: a value is stored if no invalidation of its
+	// key happened since the read started, see snapshot.
+	valueTier[V any] struct {
+		mu    sync.RWMutex              // write-locked by invalidations, see set.
+		seqs  [valueCacheStripes]uint64 // invalidation sequences by key hash.
+		seed  maphash.Seed
+		cache *ristretto.Cache[string, V]
+		ttl   time.Duration
+		clone func(V) V
+	}
+)
+
+// WithValueCache enables a local tier of the size in bytes storing the decoded
+// values for the ttl, next to the bytes in the local cache. Hits return the
+// shared value, or a copy when WithValueClone is set, without decoding. The size
+// of a value is estimated by its encoded size, or by WithValueSize.
+func WithValueCache[V any](size int64, ttl time.Duration) TOption[V] {
+	return func(o *tOptions[V]) {
+		o.valueSize = size
+		o.valueTTL = ttl
+	}
+}
+
+// WithValueOnly stores the decoded values instead of the bytes in the local
+// cache, for the keys accessed through the T. It requires WithValueCache.
+func WithValueOnly[V any](valueOnly bool) TOption[V] {
+	return func(o *tOptions[V]) {
+		o.valueOnly = valueOnly
+	}
+}
+
+// WithValueClone sets the function copying the values stored in and returned
+// from the value cache, so that callers cannot modify the shared values.
+func WithValueClone[V any](clone func(V) V) TOption[V] {
+	return func(o *tOptions[V]) {
+		o.clone = clone
+	}
+}
+
+// WithValueSize sets the function estimating the size in bytes of a value in
+// the value cache, instead of its encoded size.
+func WithValueSize[V any](sizeOf func(V) int64) TOption[V] {
+	return func(o *tOptions[V]) {
+		o.sizeOf = sizeOf
+	}
+}
+
+func newValueTier[V any](o tOptions[V]) *valueTier[V] {
+	cache, err := ristretto.NewCache[string, V](&ristretto.Config[string, V]{
+		NumCounters: valueCacheNumCounters,
+		MaxCost:     o.valueSize,
+		BufferItems: valueCacheBufferItems,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &valueTier[V]{
+		seed:  maphash.MakeSeed(),
+		cache: cache,
+		ttl:   o.valueTTL,
+		clone: o.clone,
+	}
+}
+
+// get returns the value of the key, copied by the clone function if any.
+func (t *valueTier[V]) get(key string) (V, bool) {
+	v, ok := t.cache.Get(key)
+	if ok && t.clone != nil {
+		v = t.clone(v)
+	}
+
+	return v, ok
+}
+
+// snapshot returns the invalidation sequence of the key to pass to set,
+// taken before reading the value from the other tiers.
+func (t *valueTier[V]) snapshot(key string) uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.seqs[t.stripe(key)]
+}
+
+// set stores the value unless the key was invalidated since the snapshot,
+// so that a value read before an update is never stored after it.
+func (t *valueTier[V]) set(key string, v V, size int64, seq uint64) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.seqs[t.stripe(key)] != seq {
+		return
+	}
+	t.cache.SetWithTTL(key, v, size, t.ttl)
+}
+
+func (t *valueTier[V]) Del(key string) {
+	t.mu.Lock()
+	t.seqs[t.stripe(key)]++
+	t.cache.Del(key)
+	t.mu.Unlock()
+}
+
+func (t *valueTier[V]) Clear() {
+	t.mu.Lock()
+	for i := range t.seqs {
+		t.seqs[i]++
+	}
+	t.cache.Clear()
+	t.mu.Unlock()
+}
+
+func (t *valueTier[V]) stripe(key string) uint64 {
+	return maphash.String(t.seed, key) % valueCacheStripes
+}
+
+// DelPrefix clears the tier, which does not keep its keys.
+func (t *valueTier[V]) DelPrefix(string) {
+	t.Clear()
+}
+
+func (c *jetCache) addLocalTier(tier localTier) {
+	c.tiersMu.Lock()
+	c.tiers = append(c.tiers, tier)
+	c.tiersMu.Unlock()
+}
+
+func (c *jetCache) hasLocalTiers() bool {
+	c.tiersMu.RLock()
+	defer c.tiersMu.RUnlock()
+
+	return len(c.tiers) > 0
+}
+
+func (c *jetCache) delLocalTiers(key string) {
+	c.tiersMu.RLock()
+	defer c.tiersMu.RUnlock()
+
+	for _, tier := range c.tiers {
+		tier.Del(key)
+	}
+}
+
+func (c *jetCache) clearLocalTiers() {
+	c.tiersMu.RLock()
+	defer c.tiersMu.RUnlock()
+
+	for _, tier := range c.tiers {
+		tier.Clear()
+	}
+}
+
+func (c *jetCache) delLocalTiersPrefix(prefix string) {
+	c.tiersMu.RLock()
+	defer c.tiersMu.RUnlock()
+
+	for _, tier := range c.tiers {
+		tier.DelPrefix(prefix)
+	}
+}
@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+func TestValueCache(t *testing.T) {
+	ctx := context.Background()
+	c := New(WithRemote(remote.NewGoRedisV9Adapter(newRdb())), WithLocal(localNew(freeCache)))
+	defer c.Close()
+
+	var loads int
+	fn := func(_ context.Context, id int) (*object, error) {
+		loads++
+		return &object{Str: "str", Num: id}, nil
+	}
+
+	t.Run("get", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithValueCache[*object](1<<20, time.Minute))
+		// the first read loads and writes the value, the next one fills the value cache.
+		v1, err := cacheT.Get(ctx, "get", 1, fn)
+		assert.NoError(t, err)
+		_, ok := cacheT.values.get("get:1")
+		assert.False(t, ok)
+
+		v2, err := cacheT.Get(ctx, "get", 1, fn)
+		assert.NoError(t, err)
+		assert.Equal(t, v1, v2)
+		cacheT.values.cache.Wait()
+
+		v3, err := cacheT.Get(ctx, "get", 1, fn)
+		assert.NoError(t, err)
+		v4, err := cacheT.Get(ctx, "get", 1, fn)
+		assert.NoError(t, err)
+		assert.Same(t, v3, v4)
+		assert.Equal(t, 1, loads)
+	})
+
+	t.Run("clone", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithValueCache[*object](1<<20, time.Minute),
+			WithValueClone(func(v *object) *object {
+				cp := *v
+				return &cp
+			}),
+			WithValueSize(func(*object) int64 { return 1 }))
+		_, _ = cacheT.Get(ctx, "clone", 1, fn)
+		_, _ = cacheT.Get(ctx, "clone", 1, fn)
+		cacheT.values.cache.Wait()
+
+		v1, err := cacheT.Get(ctx, "clone", 1, fn)
+		assert.NoError(t, err)
+		v1.Str = "modified"
+		v2, err := cacheT.Get(ctx, "clone", 1, fn)
+		assert.NoError(t, err)
+		assert.NotSame(t, v1, v2)
+		assert.Equal(t, "str", v2.Str)
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithValueCache[*object](1<<20, time.Minute))
+		_, _ = cacheT.Get(ctx, "invalidate", 1, fn)
+		_, _ = cacheT.Get(ctx, "invalidate", 1, fn)
+		cacheT.values.cache.Wait()
+
+		assert.NoError(t, cacheT.Set(ctx, "invalidate", 1, &object{Str: "new", Num: 1}))
+		v, err := cacheT.Get(ctx, "invalidate", 1, fn)
+		assert.NoError(t, err)
+		assert.Equal(t, "new", v.Str)
+
+		_, _ = cacheT.Get(ctx, "invalidate", 1, fn)
+		cacheT.values.cache.Wait()
+		c.(LocalController).HandleEvent(&Event{SourceID: "other", EventType: EventTypeDelete, Keys: []string{"invalidate:1"}})
+		_, ok := cacheT.values.get("invalidate:1")
+		assert.False(t, ok)
+
+		_, _ = cacheT.Get(ctx, "invalidate", 1, fn)
+		cacheT.values.cache.Wait()
+		assert.NoError(t, c.(LocalController).ClearLocalCache())
+		_, ok = cacheT.values.get("invalidate:1")
+		assert.False(t, ok)
+	})
+
+	t.Run("value only", func(t *testing.T) {
+		onlyC := New(WithRemote(remote.NewGoRedisV9Adapter(newRdb())), WithLocal(localNew(freeCache)))
+		defer onlyC.Close()
+		cacheT := NewT[int, *object](onlyC, WithValueCache[*object](1<<20, time.Minute), WithValueOnly[*object](true))
+
+		_, _ = cacheT.Get(ctx, "only", 1, fn)
+		ret := cacheT.MGet(ctx, "only", []int{1, 2}, func(_ context.Context, ids []int) (map[int]*object, error) {
+			return map[int]*object{2: {Str: "str", Num: 2}}, nil
+		})
+		assert.Len(t, ret, 2)
+		cacheT.values.cache.Wait()
+
+		n, err := onlyC.(LocalController).LocalCacheLen()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+		_, ok := cacheT.values.get("only:1")
+		assert.True(t, ok)
+
+		v, err := cacheT.Get(ctx, "only", 1, fn)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v.Num)
+	})
+
+	t.Run("mget", func(t *testing.T) {
+		cacheT := NewT[int, *object](c, WithValueCache[*object](1<<20, time.Minute))
+		mfn := func(_ context.Context, ids []int) (map[int]*object, error) {
+			ret := make(map[int]*object, len(ids))
+			for _, id := range ids {
+				ret[id] = &object{Str: "str", Num: id}
+			}
+			return ret, nil
+		}
+		_ = cacheT.MGet(ctx, "mget", []int{1, 2}, mfn)
+		_ = cacheT.MGet(ctx, "mget", []int{1, 2}, mfn)
+		cacheT.values.cache.Wait()
+
+		ret1 := cacheT.MGet(ctx, "mget", []int{1, 2}, mfn)
+		ret2 := cacheT.MGet(ctx, "mget", []int{1, 2}, mfn)
+		assert.Len(t, ret1, 2)
+		assert.Same(t, ret1[1], ret2[1])
+	})
+}
@@ -0,0 +1,280 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/mgtv-tech/jetcache-go/logger"
+)
+
+const (
+	defaultWriteQueueSize    = 1024
+	defaultWriteRetries      = 3
+	defaultWriteRetryBackoff = 100 * time.Millisecond
+	defaultWriteFlushTimeout = 10 * time.Second
+)
+
+const (
+	// WriteThrough writes the data source first, then the cache. A failed
+	// write returns its error and leaves the cache untouched.
+	WriteThrough WriteMode = iota
+	// WriteBehind writes the cache first, and the data source asynchronously
+	// through a bounded queue. Repeated writes of a pending key are coalesced.
+	// A value failing to reach the cache is not queued.
+	WriteBehind
+)
+
+var (
+	ErrWriteQueueFull   = errors.New("cache: write-behind queue is full")
+	ErrWriteQueueClosed = errors.New("cache: write-behind queue is closed")
+)
+
+type (
+	// WriteMode is the mode of the Writer, see WithWriteMode.
+	WriteMode int
+
+	// Writer writes the values set in and the keys deleted from the cache to
+	// the data source, see WithWriter.
+	Writer interface {
+		Write(ctx context.Context, key string, val any) error
+		Delete(ctx context.Context, key string) error
+	}
+
+	// writeOp is a pending write of a key to the data source.
+	writeOp struct {
+		key     string
+		val     any
+		del     bool
+		attempt int       // number of failed attempts.
+		retryAt time.Time // when the failed op is due again.
+	}
+
+	// writeQueue is the bounded queue of the write-behind mode, flushed in
+	// order by a single goroutine.
+	writeQueue struct {
+		writer  Writer
+		ctx     context.Context // canceled to abort the flush, see close.
+		cancel  context.CancelFunc
+		size    int
+		retries int
+		backoff time.Duration
+		mu      sync.Mutex
+		keys    []string            // pending keys in write order, guarded by mu.
+		ops     map[string]*writeOp // pending writes by key, guarded by mu.
+		closed  bool
+		wakeCh  chan struct{}
+		doneCh  chan struct{} // closed once the flusher returns.
+	}
+)
+
+// write writes the item set by Set to the data source, see WithWriter. In the
+// write-behind mode, b is the value marshalled by set, and the queued value is
+// a snapshot decoded from it, so the caller may modify the value after Set.
+func (c *jetCache) write(item *item, b []byte) error {
+	if c.writeMode == WriteBehind {
+		val, err := c.snapshot(item.value, b)
+		if err != nil {
+			return err
+		}
+		return c.writes.push(&writeOp{key: item.key, val: val})
+	}
+
+	return c.writer.Write(item.Context(), item.key, item.value)
+}
+
+// snapshot returns a copy of val of the same type, decoded from its marshalled
+// value b.
+func (c *jetCache) snapshot(val any, b []byte) (any, error) {
+	switch val.(type) {
+	case nil, string:
+		return val, nil
+	case []byte:
+		return bytes.Clone(b), nil
+	}
+
+	ptr := reflect.New(reflect.TypeOf(val))
+	if err := c.Unmarshal(b, ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("snapshot#c.Unmarshal(%T) error(%v)", val, err)
+	}
+
+	return ptr.Elem().Interface(), nil
+}
+
+// writeDelete deletes the key from the data source, see WithWriter.
+func (c *jetCache) writeDelete(ctx context.Context, key string) error {
+	if c.writeMode == WriteBehind {
+		return c.writes.push(&writeOp{key: key, del: true})
+	}
+
+	return c.writer.Delete(ctx, key)
+}
+
+func newWriteQueue(o Options) *writeQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &writeQueue{
+		writer:  o.writer,
+		ctx:     ctx,
+		cancel:  cancel,
+		size:    o.writeQueueSize,
+		retries: o.writeRetries,
+		backoff: o.writeRetryBackoff,
+		ops:     make(map[string]*writeOp),
+		wakeCh:  make(chan struct{}, 1),
+		doneCh:  make(chan struct{}),
+	}
+	go q.run()
+
+	return q
+}
+
+// push queues the write, replacing the pending write of the same key if any.
+func (q *writeQueue) push(op *writeOp) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrWriteQueueClosed
+	}
+	if pending, ok := q.ops[op.key]; ok {
+		*pending = *op
+		return nil
+	}
+	if len(q.keys) >= q.size {
+		return ErrWriteQueueFull
+	}
+
+	q.keys = append(q.keys, op.key)
+	q.ops[op.key] = op
+	select {
+	case q.wakeCh <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// pop returns the oldest pending write that is due, or else the delay until
+// the next one is due, and whether the queue is closed and empty. Once the
+// flush is aborted, every pending write is due.
+func (q *writeQueue) pop() (op *writeOp, wait time.Duration, closed bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.keys) == 0 {
+		return nil, 0, q.closed
+	}
+	now, aborted := time.Now(), q.ctx.Err() != nil
+	for i, key := range q.keys {
+		op = q.ops[key]
+		if !aborted && op.retryAt.After(now) {
+			if d := op.retryAt.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		if i == 0 {
+			q.keys = q.keys[1:]
+		} else {
+			q.keys = append(q.keys[:i], q.keys[i+1:]...)
+		}
+		delete(q.ops, key)
+		return op, 0, false
+	}
+
+	return nil, wait, false
+}
+
+// requeue queues the failed op again behind the pending writes, unless a newer
+// write of the same key is pending.
+func (q *writeQueue) requeue(op *writeOp) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.ops[op.key]; ok {
+		return
+	}
+	q.keys = append(q.keys, op.key)
+	q.ops[op.key] = op
+}
+
+func (q *writeQueue) run() {
+	defer close(q.doneCh)
+
+	for {
+		op, wait, closed := q.pop()
+		if op != nil {
+			q.flush(op)
+			continue
+		}
+		if closed {
+			return
+		}
+		if wait == 0 {
+			<-q.wakeCh
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-q.wakeCh:
+		case <-q.ctx.Done():
+		}
+		timer.Stop()
+	}
+}
+
+// flush writes the op to the data source. A failed op is requeued behind the
+// pending writes with an exponential backoff, so it does not hold them up. The
+// op is dropped once the retries are exhausted, or once the flush is aborted
+// by close.
+func (q *writeQueue) flush(op *writeOp) {
+	if q.ctx.Err() != nil {
+		logger.Error("writeBehind(%s) error(%v), dropped", op.key, q.ctx.Err())
+		return
+	}
+
+	var err error
+	if op.del {
+		err = q.writer.Delete(q.ctx, op.key)
+	} else {
+		err = q.writer.Write(q.ctx, op.key, op.val)
+	}
+	if err == nil {
+		return
+	}
+	if op.attempt >= q.retries {
+		logger.Error("writeBehind(%s) error(%v), dropped after %d retries", op.key, err, q.retries)
+		return
+	}
+
+	op.retryAt = time.Now().Add(q.backoff << op.attempt)
+	op.attempt++
+	q.requeue(op)
+}
+
+// close stops accepting writes and waits for the pending ones to be flushed.
+// If ctx is done first, it aborts the flush by canceling the context passed to
+// the writer, drops the pending writes and returns ctx.Err().
+func (q *writeQueue) close(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	select {
+	case q.wakeCh <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-q.doneCh:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		return ctx.Err()
+	}
+}
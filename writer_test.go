@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mgtv-tech/jetcache-go/remote"
+)
+
+type mockWriter struct {
+	mu      sync.Mutex
+	values  map[string]any
+	writes  []string
+	fails   int // number of writes to fail.
+	err     error
+	started chan struct{} // signaled by each write, if set.
+	release chan struct{} // waited for by each write, if set.
+	ctxErr  error         // error of the context of the aborted write, if any.
+}
+
+func newMockWriter() *mockWriter {
+	return &mockWriter{values: make(map[string]any), err: errors.New("write error")}
+}
+
+func (w *mockWriter) Write(ctx context.Context, key string, val any) error {
+	if w.started != nil {
+		w.started <- struct{}{}
+	}
+	if w.release != nil {
+		select {
+		case <-w.release:
+		case <-ctx.Done():
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.ctxErr = ctx.Err()
+			return ctx.Err()
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writes = append(w.writes, key)
+	if w.fails > 0 {
+		w.fails--
+		return w.err
+	}
+	w.values[key] = val
+	return nil
+}
+
+func (w *mockWriter) Delete(_ context.Context, key string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writes = append(w.writes, "-"+key)
+	delete(w.values, key)
+	return nil
+}
+
+func TestWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	writer := newMockWriter()
+	c := New(WithName("writeThrough"), WithRemote(remote.NewGoRedisV9Adapter(newRdb())), WithLocal(localNew(freeCache)),
+		WithWriter(writer))
+	defer c.Close()
+
+	assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+	assert.Equal(t, "value1", writer.values["key1"])
+	var val string
+	assert.NoError(t, c.Get(ctx, "key1", &val))
+	assert.Equal(t, "value1", val)
+
+	writer.fails = 1
+	assert.ErrorIs(t, c.Set(ctx, "key2", Value("value2")), writer.err)
+	assert.ErrorIs(t, c.Get(ctx, "key2", &val), ErrCacheMiss)
+
+	assert.NoError(t, c.Once(ctx, "key3", Value(&val), Do(func(context.Context) (any, error) {
+		return "value3", nil
+	})))
+	assert.NotContains(t, writer.values, "key3")
+
+	assert.NoError(t, c.Delete(ctx, "key1"))
+	assert.NotContains(t, writer.values, "key1")
+	assert.Equal(t, []string{"key1", "key2", "-key1"}, writer.writes)
+
+	// undecodable cached values are purged from the cache tiers only.
+	c.(*jetCache).local.Set("key4", []byte{0xc1, 0x00})
+	var obj object
+	assert.NoError(t, c.Once(ctx, "key4", Value(&obj), Do(func(context.Context) (any, error) {
+		return &object{Str: "str", Num: 4}, nil
+	})))
+	assert.Equal(t, 4, obj.Num)
+	assert.Equal(t, []string{"key1", "key2", "-key1"}, writer.writes)
+}
+
+func TestWriteBehind(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("coalesce and flush on close", func(t *testing.T) {
+		writer := newMockWriter()
+		writer.started, writer.release = make(chan struct{}, 10), make(chan struct{})
+		c := New(WithName("writeBehind"), WithRemote(remote.NewGoRedisV9Adapter(newRdb())), WithLocal(localNew(freeCache)),
+			WithWriter(writer), WithWriteMode(WriteBehind), WithWriteQueueSize(2))
+
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		<-writer.started
+		var val string
+		assert.NoError(t, c.Get(ctx, "key1", &val))
+		assert.Equal(t, "value1", val)
+
+		assert.NoError(t, c.Set(ctx, "key2", Value("value2")))
+		assert.NoError(t, c.Set(ctx, "key2", Value("value22")))
+		assert.NoError(t, c.Delete(ctx, "key3"))
+		assert.ErrorIs(t, c.Set(ctx, "key4", Value("value4")), ErrWriteQueueFull)
+		assert.ErrorIs(t, c.Get(ctx, "key4", &val), ErrCacheMiss)
+
+		close(writer.release)
+		c.Close()
+		assert.Equal(t, []string{"key1", "key2", "-key3"}, writer.writes)
+		assert.Equal(t, map[string]any{"key1": "value1", "key2": "value22"}, writer.values)
+		assert.ErrorIs(t, c.Set(ctx, "key5", Value("value5")), ErrWriteQueueClosed)
+	})
+
+	t.Run("retry", func(t *testing.T) {
+		writer := newMockWriter()
+		writer.fails = 2
+		c := New(WithName("writeRetry"), WithLocal(localNew(freeCache)),
+			WithWriter(writer), WithWriteMode(WriteBehind), WithWriteRetryBackoff(time.Millisecond))
+
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		assert.NoError(t, c.(Shutdowner).Shutdown(ctx))
+		assert.Equal(t, []string{"key1", "key1", "key1"}, writer.writes)
+		assert.Equal(t, "value1", writer.values["key1"])
+	})
+
+	t.Run("retry behind the others", func(t *testing.T) {
+		writer := newMockWriter()
+		writer.fails = 1
+		writer.started, writer.release = make(chan struct{}, 10), make(chan struct{})
+		c := New(WithName("writeRetryRequeue"), WithLocal(localNew(freeCache)),
+			WithWriter(writer), WithWriteMode(WriteBehind), WithWriteRetryBackoff(time.Millisecond))
+
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		<-writer.started
+		assert.NoError(t, c.Set(ctx, "key2", Value("value2")))
+		close(writer.release)
+		assert.NoError(t, c.(Shutdowner).Shutdown(ctx))
+		assert.Equal(t, []string{"key1", "key2", "key1"}, writer.writes)
+		assert.Equal(t, map[string]any{"key1": "value1", "key2": "value2"}, writer.values)
+	})
+
+	t.Run("snapshot", func(t *testing.T) {
+		writer := newMockWriter()
+		writer.started, writer.release = make(chan struct{}, 10), make(chan struct{})
+		c := New(WithName("writeSnapshot"), WithLocal(localNew(freeCache)),
+			WithWriter(writer), WithWriteMode(WriteBehind))
+
+		assert.NoError(t, c.Set(ctx, "key0", Value("value0")))
+		<-writer.started
+		obj, b := &object{Str: "str", Num: 1}, []byte("bytes")
+		assert.NoError(t, c.Set(ctx, "key1", Value(obj)))
+		assert.NoError(t, c.Set(ctx, "key2", Value(b)))
+		obj.Str, b[0] = "modified", 'B'
+
+		close(writer.release)
+		c.Close()
+		assert.Equal(t, &object{Str: "str", Num: 1}, writer.values["key1"])
+		assert.Equal(t, []byte("bytes"), writer.values["key2"])
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		writer := newMockWriter()
+		writer.fails = 10
+		c := New(WithName("writeDrop"), WithLocal(localNew(freeCache)),
+			WithWriter(writer), WithWriteMode(WriteBehind), WithWriteRetries(-1))
+
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		c.Close()
+		assert.Equal(t, []string{"key1"}, writer.writes)
+		assert.Empty(t, writer.values)
+	})
+	t.Run("cache write fails", func(t *testing.T) {
+		writer := newMockWriter()
+		c := New(WithName("writeCacheFail"), WithLocal(localNew(freeCache)),
+			WithWriter(writer), WithWriteMode(WriteBehind))
+
+		assert.Error(t, c.Set(ctx, "key1", Value(make(chan int))))
+		assert.NoError(t, c.Set(ctx, "key2", Value("value2")))
+		c.Close()
+		assert.Equal(t, []string{"key2"}, writer.writes)
+	})
+
+	t.Run("shutdown aborts a hung writer", func(t *testing.T) {
+		writer := newMockWriter()
+		writer.started, writer.release = make(chan struct{}, 10), make(chan struct{})
+		c := New(WithName("writeHung"), WithLocal(localNew(freeCache)),
+			WithWriter(writer), WithWriteMode(WriteBehind))
+
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		assert.NoError(t, c.Set(ctx, "key2", Value("value2")))
+		<-writer.started
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, c.(Shutdowner).Shutdown(timeoutCtx), context.DeadlineExceeded)
+		assert.Eventually(t, func() bool {
+			writer.mu.Lock()
+			defer writer.mu.Unlock()
+			return writer.ctxErr != nil
+		}, time.Second, time.Millisecond)
+		assert.ErrorIs(t, writer.ctxErr, context.Canceled)
+	})
+
+	t.Run("close times out", func(t *testing.T) {
+		writer := newMockWriter()
+		writer.started, writer.release = make(chan struct{}, 10), make(chan struct{})
+		c := New(WithName("writeCloseTimeout"), WithLocal(localNew(freeCache)),
+			WithWriter(writer), WithWriteMode(WriteBehind), WithWriteFlushTimeout(20*time.Millisecond))
+
+		assert.NoError(t, c.Set(ctx, "key1", Value("value1")))
+		<-writer.started
+		start := time.Now()
+		c.Close()
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}